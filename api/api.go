@@ -0,0 +1,139 @@
+// Package api runs dobi tasks from a Go program. It is the supported
+// entrypoint for using dobi as a library.
+package api
+
+import (
+	gocontext "context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks"
+	"github.com/dnephin/dobi/tasks/client"
+	"github.com/dnephin/dobi/tasks/events"
+	"github.com/dnephin/dobi/tasks/report"
+	docker "github.com/fsouza/go-dockerclient"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultDockerAPIVersion is the version of the docker API used when
+// DOCKER_API_VERSION is not set
+const DefaultDockerAPIVersion = "1.25"
+
+// Options for a run
+type Options struct {
+	// Filename is the path to the config file. Defaults to dobi.yaml. It is
+	// not used when Config is set.
+	Filename string
+	// Config is the config to run. It is loaded from Filename when it is nil.
+	Config *config.Config
+	// Tasks are the names of the tasks to run. Defaults to the default task
+	// from the config.
+	Tasks []string
+	// Args are passed to the command of each job in Tasks
+	Args []string
+	// Client is the docker client. Defaults to a client created from the
+	// DOCKER_* environment variables.
+	Client client.DockerClient
+
+	// Stdout and Stderr receive the output of every task. They default to
+	// os.Stdout and os.Stderr.
+	Stdout io.Writer
+	Stderr io.Writer
+	// Logger receives the log messages from the run. Defaults to the logger in
+	// the logging package.
+	Logger *log.Logger
+	// OnEvent is called with the event for each point in the lifecycle of every
+	// task. Events are sent one at a time.
+	OnEvent func(events.Event)
+	// Context cancels the run when it is done. Defaults to
+	// context.Background(). Run does not handle any signals, so a program
+	// which stops the run on SIGINT or SIGTERM cancels the Context.
+	Context gocontext.Context
+
+	// NoBindMount provides mounts as a layer in an image instead of a bind
+	// mount
+	NoBindMount bool
+	// Parallel is the maximum number of tasks to run at the same time
+	Parallel int
+	// KeepGoing continues to run every task which does not depend on a failed
+	// task
+	KeepGoing bool
+	// Timeout is the maximum time a task may run when the resource does not
+	// set a timeout
+	Timeout time.Duration
+}
+
+// Result is the outcome of a run
+type Result struct {
+	// Tasks is the outcome of each task, in the order the tasks were
+	// collected
+	Tasks []*report.TaskReport
+}
+
+// Run the tasks and return the outcome of each task. The error is the error
+// from the run, which wraps tasks.ErrInterrupted when the Context is cancelled.
+// The Result is returned even when there is an error, unless the config could
+// not be loaded.
+func Run(options Options) (*Result, error) {
+	conf, err := loadConfig(options)
+	if err != nil {
+		return nil, err
+	}
+	dockerClient := options.Client
+	if dockerClient == nil {
+		dockerClient, err = NewDockerClient()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create client: %s", err)
+		}
+	}
+	runReport := report.New()
+	listeners := []events.Listener{runReport}
+	if options.OnEvent != nil {
+		listeners = append(listeners, events.ListenerFunc(options.OnEvent))
+	}
+
+	err = tasks.Run(tasks.RunOptions{
+		Client:    dockerClient,
+		Config:    conf,
+		Tasks:     options.Tasks,
+		Args:      options.Args,
+		BindMount: !options.NoBindMount,
+		Parallel:  options.Parallel,
+		KeepGoing: options.KeepGoing,
+		Timeout:   options.Timeout,
+		Listeners: listeners,
+		Stdout:    options.Stdout,
+		Stderr:    options.Stderr,
+		Context:   options.Context,
+		Logger:    options.Logger,
+	})
+	return &Result{Tasks: runReport.Tasks}, err
+}
+
+func loadConfig(options Options) (*config.Config, error) {
+	if options.Config != nil {
+		return options.Config, nil
+	}
+	filename := options.Filename
+	if filename == "" {
+		filename = "dobi.yaml"
+	}
+	return config.Load(filename)
+}
+
+// NewDockerClient returns a docker client configured from the DOCKER_*
+// environment variables
+func NewDockerClient() (client.DockerClient, error) {
+	apiVersion := os.Getenv("DOCKER_API_VERSION")
+	if apiVersion == "" {
+		apiVersion = DefaultDockerAPIVersion
+	}
+	dockerClient, err := docker.NewVersionedClientFromEnv(apiVersion)
+	if err != nil {
+		return nil, err
+	}
+	return dockerClient, nil
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks/client"
+	"github.com/dnephin/dobi/tasks/events"
+	"github.com/dnephin/dobi/tasks/report"
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	log "github.com/sirupsen/logrus"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+func TestRun(t *testing.T) {
+	dir := fs.NewDir(t, "project")
+	defer dir.Remove()
+	defer os.Unsetenv("API_TEST_VAR") // nolint: errcheck
+
+	conf, err := config.LoadFromBytes([]byte(`
+env=vars:
+  variables: [API_TEST_VAR=set]
+alias=all:
+  tasks: [vars]
+`))
+	assert.NilError(t, err)
+	conf.WorkingDir = dir.Path()
+
+	mock := gomock.NewController(t)
+	defer mock.Finish()
+
+	logger := log.New()
+	logs := new(bytes.Buffer)
+	logger.Out = logs
+	previous := logging.Log
+
+	received := []string{}
+	result, err := Run(Options{
+		Config: conf,
+		Tasks:  []string{"all"},
+		Client: client.NewMockDockerClient(mock),
+		Logger: logger,
+		Stdout: new(bytes.Buffer),
+		OnEvent: func(event events.Event) {
+			received = append(received, string(event.Type)+" "+event.Task.Name())
+		},
+	})
+	assert.NilError(t, err)
+
+	expected := []*report.TaskReport{
+		{Name: "vars:set", Status: report.Succeeded, Reason: "API_TEST_VAR will be changed"},
+		{Name: "all:run", Status: report.Succeeded, Reason: "dependencies were modified"},
+	}
+	assert.Check(t, is.DeepEqual(expected, result.Tasks,
+		cmpopts.IgnoreFields(report.TaskReport{}, "Duration", "Seconds")))
+	assert.Check(t, is.Contains(received, "stale vars:set"))
+	assert.Check(t, is.Contains(received, "finished all:run"))
+	assert.Check(t, is.Contains(logs.String(), "Done"))
+	assert.Check(t, logging.Log == previous)
+}
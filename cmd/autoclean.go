@@ -31,10 +31,11 @@ func runClean(opts *dobiOptions) error {
 	}
 
 	return tasks.Run(tasks.RunOptions{
-		Client: client,
-		Config: conf,
-		Tasks:  removeTasks(conf),
-		Quiet:  opts.quiet,
+		Client:        client,
+		Config:        conf,
+		Tasks:         removeTasks(conf),
+		Quiet:         opts.quiet,
+		HandleSignals: true,
 	})
 }
 
@@ -3,19 +3,22 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"time"
 
+	"github.com/dnephin/dobi/api"
 	"github.com/dnephin/dobi/config"
 	"github.com/dnephin/dobi/logging"
 	"github.com/dnephin/dobi/tasks"
 	"github.com/dnephin/dobi/tasks/client"
-	docker "github.com/fsouza/go-dockerclient"
+	"github.com/dnephin/dobi/tasks/events"
+	"github.com/dnephin/dobi/tasks/report"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
 const (
 	// DefaultDockerAPIVersion is the default version of the docker API to use
-	DefaultDockerAPIVersion = "1.25"
+	DefaultDockerAPIVersion = api.DefaultDockerAPIVersion
 )
 
 var (
@@ -29,7 +32,15 @@ type dobiOptions struct {
 	verbose     bool
 	quiet       bool
 	noBindMount bool
+	parallel    int
+	dryRun      bool
+	keepGoing   bool
+	timeout     time.Duration
+	eventsFile  string
+	reports     []string
+	traceFile   string
 	tasks       []string
+	args        []string
 	version     bool
 }
 
@@ -38,14 +49,14 @@ func NewRootCommand() *cobra.Command {
 	var opts dobiOptions
 
 	cmd := &cobra.Command{
-		Use:              "dobi [flags] RESOURCE[:ACTION] [RESOURCE[:ACTION]...]",
+		Use:              "dobi [flags] RESOURCE[:ACTION] [RESOURCE[:ACTION]...] [-- ARGS...]",
 		Short:            "A build automation tool for Docker applications",
 		SilenceUsage:     true,
 		SilenceErrors:    true,
 		TraverseChildren: true,
 		Args:             cobra.ArbitraryArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			opts.tasks = args
+			opts.tasks, opts.args = splitArgs(args, cmd.ArgsLenAtDash())
 			return runDobi(opts)
 		},
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
@@ -63,16 +74,98 @@ func NewRootCommand() *cobra.Command {
 		"no-bind-mount",
 		defaultBoolValue("DOBI_NO_BIND_MOUNT"),
 		"Provide mounts as a layer in an image instead of a bind mount")
+	flags.IntVar(
+		&opts.parallel,
+		"parallel",
+		1,
+		"Maximum number of independent tasks to run at the same time")
+	flags.BoolVar(
+		&opts.dryRun,
+		"dry-run",
+		false,
+		"Print the tasks that would run, and why, without running them")
+	flags.BoolVarP(
+		&opts.keepGoing,
+		"keep-going",
+		"k",
+		false,
+		"Continue to run tasks that do not depend on a failed task")
+	flags.DurationVar(
+		&opts.timeout,
+		"timeout",
+		0,
+		"Maximum time a task may run, for resources which do not set a timeout")
+	flags.StringVar(
+		&opts.eventsFile,
+		"events-file",
+		"",
+		"Write task events to a file as newline delimited JSON")
+	flags.StringArrayVar(
+		&opts.reports,
+		"report",
+		nil,
+		"Write a report of the run as FORMAT=PATH, where FORMAT is junit or json")
+	flags.StringVar(
+		&opts.traceFile,
+		"trace-file",
+		"",
+		"Write a timeline of the run to a file in the Chrome Trace Event format")
 	flags.BoolVar(&opts.version, "version", false, "Print version and exit")
 
 	flags.SetInterspersed(false)
 	cmd.AddCommand(
 		newListCommand(&opts),
 		newCleanCommand(&opts),
+		newGraphCommand(&opts),
+		newWatchCommand(&opts),
+		newExplainCommand(&opts),
+		newSchemaCommand(),
+		newValidateCommand(&opts),
 	)
 	return cmd
 }
 
+// Execute runs the root command with args. The subcommands which are not
+// reserved resource names are only run when the config has no resource with
+// the same name, otherwise the resource is run as a task.
+func Execute(args []string) error {
+	cmd := NewRootCommand()
+	removeShadowedCommands(cmd, args)
+	cmd.SetArgs(args)
+	return cmd.Execute()
+}
+
+// removeShadowedCommands removes the subcommand selected by args from cmd when
+// the config has a resource with the same name as the subcommand. The config
+// is only loaded when args select a subcommand which can be shadowed.
+func removeShadowedCommands(cmd *cobra.Command, args []string) {
+	probe := NewRootCommand()
+	subcommand, _, err := probe.Traverse(args)
+	if err != nil || subcommand == probe || config.IsReservedName(subcommand.Name()) {
+		return
+	}
+	flags := probe.Flags()
+	filename := flags.Lookup("filename").Value.String()
+	conf, err := config.Load(filename)
+	if err != nil {
+		// logging is not initialized until the command runs
+		verbose, _ := flags.GetBool("verbose")
+		quiet, _ := flags.GetBool("quiet")
+		initLogging(verbose, quiet)
+		logging.Log.Debugf("Running the %s command, failed to load %s: %s",
+			subcommand.Name(), filename, err)
+		return
+	}
+	if _, exists := conf.Resources[subcommand.Name()]; !exists {
+		return
+	}
+	for _, child := range cmd.Commands() {
+		if child.Name() == subcommand.Name() {
+			cmd.RemoveCommand(child)
+		}
+	}
+}
+
 func runDobi(opts dobiOptions) error {
 	if opts.version {
 		printVersion()
@@ -89,13 +182,84 @@ func runDobi(opts dobiOptions) error {
 		return fmt.Errorf("failed to create client: %s", err)
 	}
 
-	return tasks.Run(tasks.RunOptions{
-		Client:    client,
-		Config:    conf,
-		Tasks:     opts.tasks,
-		Quiet:     opts.quiet,
-		BindMount: !opts.noBindMount,
+	reportOutputs, err := parseReportOutputs(opts.reports)
+	if err != nil {
+		return err
+	}
+	runReport := report.New()
+	runTrace := report.NewTrace()
+	recorders := []events.Listener{}
+	if len(reportOutputs) > 0 {
+		recorders = append(recorders, runReport)
+	}
+	if opts.traceFile != "" {
+		recorders = append(recorders, runTrace)
+	}
+	listeners, closeListeners, err := newListeners(opts, recorders...)
+	if err != nil {
+		return err
+	}
+	defer closeListeners()
+
+	err = tasks.Run(tasks.RunOptions{
+		Client:        client,
+		Config:        conf,
+		Tasks:         opts.tasks,
+		Quiet:         opts.quiet,
+		BindMount:     !opts.noBindMount,
+		Parallel:      opts.parallel,
+		DryRun:        opts.dryRun,
+		KeepGoing:     opts.keepGoing,
+		Timeout:       opts.timeout,
+		Listeners:     listeners,
+		Args:          opts.args,
+		HandleSignals: true,
 	})
+	if reportErr := writeReports(runReport, reportOutputs); reportErr != nil {
+		logging.Log.Warn(reportErr)
+	}
+	if opts.traceFile != "" {
+		if traceErr := writeTrace(runTrace, opts.traceFile); traceErr != nil {
+			logging.Log.Warn(traceErr)
+		}
+	}
+	return err
+}
+
+// splitArgs splits the positional arguments into task names, and the args after
+// "--" which are passed to the command of a job. A "--" before any task name is
+// removed by the flag parser, and argsLenAtDash is its position. Flags are not
+// parsed after the first task name, so a later "--" is one of the positional
+// arguments.
+func splitArgs(args []string, argsLenAtDash int) ([]string, []string) {
+	if argsLenAtDash >= 0 {
+		return args[:argsLenAtDash], args[argsLenAtDash:]
+	}
+	for i, arg := range args {
+		if arg == "--" {
+			return args[:i], args[i+1:]
+		}
+	}
+	return args, nil
+}
+
+// newListeners returns the event listeners for the options, including the
+// recorders, and a function which closes any files opened by the listeners
+func newListeners(
+	opts dobiOptions,
+	recorders ...events.Listener,
+) ([]events.Listener, func(), error) {
+	listeners := append([]events.Listener{}, recorders...)
+	if opts.eventsFile == "" {
+		return listeners, func() {}, nil
+	}
+
+	eventsFile, err := os.Create(opts.eventsFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create events file: %s", err)
+	}
+	listeners = append(listeners, events.NewJSONWriter(eventsFile, logging.Log))
+	return listeners, func() { eventsFile.Close() }, nil // nolint: errcheck
 }
 
 func initLogging(verbose, quiet bool) {
@@ -114,12 +278,8 @@ func initLogging(verbose, quiet bool) {
 }
 
 func buildClient() (client.DockerClient, error) {
-	apiVersion := os.Getenv("DOCKER_API_VERSION")
-	if apiVersion == "" {
-		apiVersion = DefaultDockerAPIVersion
-	}
 	// TODO: args for client
-	client, err := docker.NewVersionedClientFromEnv(apiVersion)
+	client, err := api.NewDockerClient()
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tasks, args := splitArgs([]string{"test", "lint", "--", "-run", "TestFoo"}, -1)
+	assert.Check(t, is.DeepEqual([]string{"test", "lint"}, tasks))
+	assert.Check(t, is.DeepEqual([]string{"-run", "TestFoo"}, args))
+
+	tasks, args = splitArgs([]string{"test"}, -1)
+	assert.Check(t, is.DeepEqual([]string{"test"}, tasks))
+	assert.Check(t, is.Len(args, 0))
+}
+
+func TestRootCommandSplitsArgs(t *testing.T) {
+	var testcases = []struct {
+		args          []string
+		expectedTasks []string
+		expectedArgs  []string
+	}{
+		{
+			args:          []string{"-v", "test", "--", "-run", "TestFoo"},
+			expectedTasks: []string{"test"},
+			expectedArgs:  []string{"-run", "TestFoo"},
+		},
+		{
+			args:          []string{"--", "-run", "TestFoo"},
+			expectedTasks: []string{},
+			expectedArgs:  []string{"-run", "TestFoo"},
+		},
+	}
+	for _, testcase := range testcases {
+		cmd := NewRootCommand()
+		var tasks, args []string
+		cmd.RunE = func(cmd *cobra.Command, positional []string) error {
+			tasks, args = splitArgs(positional, cmd.ArgsLenAtDash())
+			return nil
+		}
+		cmd.SetArgs(testcase.args)
+		assert.NilError(t, cmd.Execute())
+		assert.Check(t, is.DeepEqual(testcase.expectedTasks, tasks))
+		assert.Check(t, is.DeepEqual(testcase.expectedArgs, args))
+	}
+}
+
+func hasCommand(cmd *cobra.Command, name string) bool {
+	for _, child := range cmd.Commands() {
+		if child.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRemoveShadowedCommands(t *testing.T) {
+	dir := fs.NewDir(t, "test-remove-shadowed-commands",
+		fs.WithFile("dobi.yaml", `
+image=builder:
+  image: example/builder
+  pull: always
+
+job=graph:
+  use: builder
+  command: make graph
+`))
+	defer dir.Remove()
+	filename := dir.Join("dobi.yaml")
+
+	cmd := NewRootCommand()
+	removeShadowedCommands(cmd, []string{"-f", filename, "graph", "--", "all"})
+	assert.Check(t, !hasCommand(cmd, "graph"), "expected graph resource to shadow command")
+	assert.Check(t, hasCommand(cmd, "watch"))
+
+	cmd = NewRootCommand()
+	removeShadowedCommands(cmd, []string{"-f", filename, "watch", "all"})
+	assert.Check(t, hasCommand(cmd, "watch"))
+	assert.Check(t, hasCommand(cmd, "graph"))
+}
+
+func TestRemoveShadowedCommandsWithInvalidConfig(t *testing.T) {
+	dir := fs.NewDir(t, "test-remove-shadowed-commands",
+		fs.WithFile("dobi.yaml", "job=graph: [not, a, mapping]"))
+	defer dir.Remove()
+
+	cmd := NewRootCommand()
+	removeShadowedCommands(cmd, []string{"-f", dir.Join("dobi.yaml"), "graph"})
+	assert.Check(t, hasCommand(cmd, "graph"))
+}
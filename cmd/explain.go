@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks"
+	"github.com/spf13/cobra"
+)
+
+func newExplainCommand(opts *dobiOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "explain [TASK...]",
+		Short: "Explain why tasks are stale or fresh",
+		Long: "Check if each task, and each of its dependencies, is stale without " +
+			"running it. The reason is printed for each task, along with the files " +
+			"and times that were compared.",
+		Args: cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runExplain(opts, args)
+		},
+	}
+}
+
+func runExplain(opts *dobiOptions, names []string) error {
+	conf, err := config.Load(opts.filename)
+	if err != nil {
+		return err
+	}
+
+	client, err := buildClient()
+	if err != nil {
+		return fmt.Errorf("failed to create client: %s", err)
+	}
+
+	return tasks.Explain(tasks.RunOptions{
+		Client:    client,
+		Config:    conf,
+		Tasks:     names,
+		Quiet:     opts.quiet,
+		BindMount: !opts.noBindMount,
+	})
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks"
+	"github.com/spf13/cobra"
+)
+
+type graphOptions struct {
+	format string
+}
+
+func newGraphCommand(opts *dobiOptions) *cobra.Command {
+	var graphOpts graphOptions
+	cmd := &cobra.Command{
+		Use:   "graph [flags] [TASK...]",
+		Short: "Print the dependency graph of tasks",
+		Long: "Print the dependency graph of tasks. If no tasks are given the " +
+			"default task is used, or every resource if there is no default.",
+		Args: cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGraph(opts, graphOpts, args)
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(
+		&graphOpts.format, "format", "dot",
+		"Output format, one of: dot, mermaid, json")
+	return cmd
+}
+
+func runGraph(opts *dobiOptions, graphOpts graphOptions, names []string) error {
+	conf, err := config.Load(opts.filename)
+	if err != nil {
+		return err
+	}
+
+	graph, err := tasks.Graph(tasks.RunOptions{Config: conf, Tasks: names})
+	if err != nil {
+		return err
+	}
+	return writeGraph(os.Stdout, graphOpts.format, graph)
+}
+
+func writeGraph(out io.Writer, format string, graph []tasks.GraphNode) error {
+	switch format {
+	case "dot":
+		_, err := io.WriteString(out, formatDot(graph))
+		return err
+	case "mermaid":
+		_, err := io.WriteString(out, formatMermaid(graph))
+		return err
+	case "json":
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(graph)
+	default:
+		return fmt.Errorf("invalid graph format %q", format)
+	}
+}
+
+func formatDot(graph []tasks.GraphNode) string {
+	lines := []string{"digraph dobi {"}
+	for _, node := range graph {
+		lines = append(lines, fmt.Sprintf("  %q [label=%q];",
+			node.Name, fmt.Sprintf("%s\n(%s)", node.Name, node.Type)))
+	}
+	for _, node := range graph {
+		for _, dep := range node.Dependencies {
+			lines = append(lines, fmt.Sprintf("  %q -> %q;", node.Name, dep))
+		}
+		eachHook(node, func(kind, hook string) {
+			lines = append(lines, fmt.Sprintf("  %q -> %q [style=dashed, label=%q];",
+				node.Name, hook, kind))
+		})
+	}
+	lines = append(lines, "}")
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func formatMermaid(graph []tasks.GraphNode) string {
+	ids := make(map[string]string)
+	lines := []string{"graph TD"}
+	for i, node := range graph {
+		ids[node.Name] = fmt.Sprintf("n%d", i)
+		lines = append(lines, fmt.Sprintf("  %s[\"%s (%s)\"]",
+			ids[node.Name], node.Name, node.Type))
+	}
+	for _, node := range graph {
+		for _, dep := range node.Dependencies {
+			lines = append(lines, fmt.Sprintf("  %s --> %s", ids[node.Name], ids[dep]))
+		}
+		eachHook(node, func(kind, hook string) {
+			lines = append(lines, fmt.Sprintf("  %s -. %s .-> %s",
+				ids[node.Name], kind, ids[hook]))
+		})
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// eachHook calls each with the kind of hook and the name of the task, for
+// every task run by a hook of the node
+func eachHook(node tasks.GraphNode, each func(kind, hook string)) {
+	if node.Hooks == nil {
+		return
+	}
+	for _, hook := range node.Hooks.Before {
+		each("before", hook)
+	}
+	for _, hook := range node.Hooks.After {
+		each("after", hook)
+	}
+	for _, hook := range node.Hooks.OnFailure {
+		each("on-failure", hook)
+	}
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/dnephin/dobi/tasks"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+var testGraph = []tasks.GraphNode{
+	{Name: "builder:build", Resource: "builder", Type: "image", Dependencies: []string{}},
+	{Name: "source:create", Resource: "source", Type: "mount", Dependencies: []string{}},
+	{
+		Name:         "test:run",
+		Resource:     "test",
+		Type:         "job",
+		Dependencies: []string{"builder:build", "source:create"},
+	},
+}
+
+func TestFormatDot(t *testing.T) {
+	expected := `digraph dobi {
+  "builder:build" [label="builder:build\n(image)"];
+  "source:create" [label="source:create\n(mount)"];
+  "test:run" [label="test:run\n(job)"];
+  "test:run" -> "builder:build";
+  "test:run" -> "source:create";
+}
+`
+	assert.Check(t, is.Equal(expected, formatDot(testGraph)))
+}
+
+func TestFormatMermaid(t *testing.T) {
+	expected := `graph TD
+  n0["builder:build (image)"]
+  n1["source:create (mount)"]
+  n2["test:run (job)"]
+  n2 --> n0
+  n2 --> n1
+`
+	assert.Check(t, is.Equal(expected, formatMermaid(testGraph)))
+}
+
+var testGraphWithHooks = []tasks.GraphNode{
+	{Name: "db:up", Resource: "db", Type: "compose", Dependencies: []string{}},
+	{
+		Name:         "test:run",
+		Resource:     "test",
+		Type:         "job",
+		Dependencies: []string{},
+		Hooks:        &tasks.GraphHooks{Before: []string{"db:up"}, After: []string{"db:down"}},
+	},
+	{Name: "db:down", Resource: "db", Type: "compose", Dependencies: []string{}},
+}
+
+func TestFormatDotWithHooks(t *testing.T) {
+	expected := `digraph dobi {
+  "db:up" [label="db:up\n(compose)"];
+  "test:run" [label="test:run\n(job)"];
+  "db:down" [label="db:down\n(compose)"];
+  "test:run" -> "db:up" [style=dashed, label="before"];
+  "test:run" -> "db:down" [style=dashed, label="after"];
+}
+`
+	assert.Check(t, is.Equal(expected, formatDot(testGraphWithHooks)))
+}
+
+func TestFormatMermaidWithHooks(t *testing.T) {
+	expected := `graph TD
+  n0["db:up (compose)"]
+  n1["test:run (job)"]
+  n2["db:down (compose)"]
+  n1 -. before .-> n0
+  n1 -. after .-> n2
+`
+	assert.Check(t, is.Equal(expected, formatMermaid(testGraphWithHooks)))
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"path/filepath"
 	"sort"
 
 	"strings"
@@ -95,18 +96,11 @@ func filterResourcesTags(conf *config.Config, listOpts listOptions) []resourceGr
 					currentGroupIndex = len(tags) - 1
 				}
 				tags[currentGroupIndex].resources = append(tags[currentGroupIndex].resources,
-					namedResource{
-						name:     name,
-						resource: res,
-					})
+					newNamedResource(conf, name))
 			}
 		} else {
 			if listOpts.all {
-				tags[0].resources = append(tags[0].resources,
-					namedResource{
-						name:     name,
-						resource: res,
-					})
+				tags[0].resources = append(tags[0].resources, newNamedResource(conf, name))
 			}
 		}
 	}
@@ -119,7 +113,7 @@ func filterResources(conf *config.Config, listOpts listOptions) []namedResource
 	for _, name := range conf.Sorted() {
 		res := conf.Resources[name]
 		if include(res, listOpts) {
-			resources = append(resources, namedResource{name: name, resource: res})
+			resources = append(resources, newNamedResource(conf, name))
 		}
 	}
 	return resources
@@ -133,6 +127,18 @@ type resourceGroup struct {
 type namedResource struct {
 	name     string
 	resource config.Resource
+	// source is the included config file which defines the resource. It is
+	// empty when the resource is defined in the config file which was loaded.
+	source string
+}
+
+func newNamedResource(conf *config.Config, name string) namedResource {
+	named := namedResource{name: name, resource: conf.Resources[name]}
+	source := conf.Source(name)
+	if path, err := filepath.Abs(source); err == nil && path != conf.FilePath {
+		named.source = source
+	}
+	return named
 }
 
 func (n namedResource) Describe() string {
@@ -154,6 +160,9 @@ func getDescriptions(resources []namedResource) []string {
 	lines := []string{}
 	for _, named := range resources {
 		line := fmt.Sprintf("%-20s %s", named.name, named.Describe())
+		if named.source != "" {
+			line += fmt.Sprintf(" (from %s)", named.source)
+		}
 		lines = append(lines, line)
 	}
 	return lines
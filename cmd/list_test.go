@@ -57,3 +57,20 @@ func TestInclude(t *testing.T) {
 		assert.Check(t, is.Equal(testcase.expected, actual))
 	}
 }
+
+func TestGetDescriptionsWithSource(t *testing.T) {
+	resource := &testconfig.FakeResource{
+		Annotations: config.Annotations{
+			Annotations: config.AnnotationFields{Description: "Run the tests"},
+		},
+	}
+	resources := []namedResource{
+		{name: "lint", resource: resource},
+		{name: "shared.test", resource: resource, source: "ci/shared.yaml"},
+	}
+	expected := []string{
+		"lint                 Run the tests",
+		"shared.test          Run the tests (from ci/shared.yaml)",
+	}
+	assert.Check(t, is.DeepEqual(expected, getDescriptions(resources)))
+}
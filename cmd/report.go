@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dnephin/dobi/tasks/report"
+)
+
+type reportOutput struct {
+	format string
+	path   string
+}
+
+func parseReportOutputs(values []string) ([]reportOutput, error) {
+	outputs := []reportOutput{}
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return nil, fmt.Errorf("invalid report %q, expected FORMAT=PATH", value)
+		}
+		switch parts[0] {
+		case "junit", "json":
+		default:
+			return nil, fmt.Errorf("invalid report format %q, must be junit or json", parts[0])
+		}
+		outputs = append(outputs, reportOutput{format: parts[0], path: parts[1]})
+	}
+	return outputs, nil
+}
+
+func writeReports(runReport *report.Report, outputs []reportOutput) error {
+	for _, output := range outputs {
+		if err := writeReport(runReport, output); err != nil {
+			return fmt.Errorf("failed to write %s report: %s", output.format, err)
+		}
+	}
+	return nil
+}
+
+func writeReport(runReport *report.Report, output reportOutput) error {
+	file, err := os.Create(output.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close() // nolint: errcheck
+
+	var write func(io.Writer) error
+	switch output.format {
+	case "junit":
+		write = runReport.WriteJUnit
+	default:
+		write = runReport.WriteJSON
+	}
+	if err := write(file); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func writeTrace(runTrace *report.Trace, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to write trace: %s", err)
+	}
+	defer file.Close() // nolint: errcheck
+
+	if err := runTrace.Write(file); err != nil {
+		return fmt.Errorf("failed to write trace: %s", err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+var cmpReportOutput = cmp.AllowUnexported(reportOutput{})
+
+func TestParseReportOutputs(t *testing.T) {
+	outputs, err := parseReportOutputs([]string{"junit=dist/junit.xml", "json=report.json"})
+	assert.NilError(t, err)
+	expected := []reportOutput{
+		{format: "junit", path: "dist/junit.xml"},
+		{format: "json", path: "report.json"},
+	}
+	assert.Check(t, is.DeepEqual(expected, outputs, cmpReportOutput))
+}
+
+func TestParseReportOutputsInvalid(t *testing.T) {
+	_, err := parseReportOutputs([]string{"junit"})
+	assert.Check(t, is.Error(err, `invalid report "junit", expected FORMAT=PATH`))
+
+	_, err = parseReportOutputs([]string{"html=report.html"})
+	assert.Check(t, is.Error(err, `invalid report format "html", must be junit or json`))
+}
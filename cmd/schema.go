@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/spf13/cobra"
+)
+
+func newSchemaCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "schema",
+		Short: "Print a JSON Schema for the config file",
+		Long: "Print a JSON Schema for the config file, which can be used by an " +
+			"editor to complete and validate the config.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return writeSchema(os.Stdout)
+		},
+	}
+}
+
+func writeSchema(out io.Writer) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(config.JSONSchema())
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/spf13/cobra"
+)
+
+func newValidateCommand(opts *dobiOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate",
+		Short: "Check the config file and print every problem",
+		Long: "Check the config file and print every problem, instead of stopping " +
+			"at the first one. Exits with a non-zero status if there are any problems.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runValidate(os.Stdout, opts.filename)
+		},
+	}
+}
+
+func runValidate(out io.Writer, filename string) error {
+	_, errs := config.LoadAll(filename)
+	for _, err := range errs {
+		fmt.Fprintln(out, err)
+	}
+	switch len(errs) {
+	case 0:
+		fmt.Fprintf(out, "%s is valid\n", filename)
+		return nil
+	case 1:
+		return fmt.Errorf("found 1 problem in %s", filename)
+	default:
+		return fmt.Errorf("found %d problems in %s", len(errs), filename)
+	}
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks"
+	"github.com/spf13/cobra"
+)
+
+type watchOptions struct {
+	interval time.Duration
+}
+
+func newWatchCommand(opts *dobiOptions) *cobra.Command {
+	var watchOpts watchOptions
+	cmd := &cobra.Command{
+		Use:   "watch [flags] [TASK...]",
+		Short: "Run tasks, and run them again when their files change",
+		Long: "Run tasks, and then watch the files used by each task. Job sources " +
+			"and bind mounts, and image build contexts are watched. When the " +
+			"files change the task, and every task which depends on it, is run again.",
+		Args: cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if watchOpts.interval <= 0 {
+				return fmt.Errorf("--interval must be greater than 0, not %s", watchOpts.interval)
+			}
+			return runWatch(opts, watchOpts, args)
+		},
+	}
+	flags := cmd.Flags()
+	flags.DurationVar(
+		&watchOpts.interval, "interval", 500*time.Millisecond,
+		"Time between checks for changed files. Every check walks the watched files, "+
+			"so a longer interval uses less CPU on large projects")
+	return cmd
+}
+
+func runWatch(opts *dobiOptions, watchOpts watchOptions, names []string) error {
+	conf, err := config.Load(opts.filename)
+	if err != nil {
+		return err
+	}
+
+	client, err := buildClient()
+	if err != nil {
+		return fmt.Errorf("failed to create client: %s", err)
+	}
+
+	return tasks.Watch(tasks.RunOptions{
+		Client:    client,
+		Config:    conf,
+		Tasks:     names,
+		Quiet:     opts.quiet,
+		BindMount: !opts.noBindMount,
+		Timeout:   opts.timeout,
+	}, watchOpts.interval)
+}
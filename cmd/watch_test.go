@@ -0,0 +1,17 @@
+package cmd
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestWatchCommandRejectsInvalidInterval(t *testing.T) {
+	for _, interval := range []string{"0s", "-1s"} {
+		cmd := newWatchCommand(&dobiOptions{})
+		cmd.SetArgs([]string{"--interval", interval})
+		cmd.SilenceUsage = true
+		err := cmd.Execute()
+		assert.ErrorContains(t, err, "--interval must be greater than 0")
+	}
+}
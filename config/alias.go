@@ -23,6 +23,8 @@ type AliasConfig struct {
 	// type: list of tasks
 	Tasks []string `config:"required"`
 	Annotations
+	Conditional
+	Hookable
 }
 
 // Dependencies returns the list of tasks
@@ -30,6 +32,10 @@ func (c *AliasConfig) Dependencies() []string {
 	return c.Tasks
 }
 
+func (c *AliasConfig) qualifyReferences(namespace string) {
+	c.Tasks = qualifyNames(namespace, c.Tasks)
+}
+
 // Validate the resource
 func (c *AliasConfig) Validate(path pth.Path, config *Config) *pth.Error {
 	return nil
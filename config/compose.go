@@ -39,8 +39,15 @@ type ComposeConfig struct {
 	// StopGrace Seconds to wait for containers to stop before killing them.
 	// default: ``5``
 	StopGrace int
+	// Timeout The maximum time ``docker-compose`` may run before it is
+	// killed. Defaults to the value of the ``--timeout`` flag.
+	// type: duration string
+	// example: ``5m``
+	Timeout Duration
 	Dependent
 	Annotations
+	Conditional
+	Hookable
 }
 
 // StopGraceString returns StopGrace as a string
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// condition is a parsed when expression. A condition without an operator is
+// true when the left value is not empty.
+type condition struct {
+	left     string
+	operator string
+	right    string
+	negate   bool
+}
+
+const (
+	opEqual    = "=="
+	opNotEqual = "!="
+)
+
+func parseCondition(expr string) (condition, error) {
+	expr = strings.TrimSpace(expr)
+	for _, operator := range []string{opEqual, opNotEqual} {
+		parts := strings.SplitN(expr, operator, 2)
+		if len(parts) != 2 {
+			continue
+		}
+		cond := condition{
+			left:     strings.TrimSpace(parts[0]),
+			operator: operator,
+			right:    strings.TrimSpace(parts[1]),
+		}
+		switch {
+		case cond.left == "" || cond.right == "":
+			return cond, fmt.Errorf(
+				"invalid when %q, expected a value on both sides of %s", expr, operator)
+		case hasOperator(cond.left) || hasOperator(cond.right):
+			return cond, fmt.Errorf(
+				"invalid when %q, only one comparison is supported", expr)
+		}
+		return cond, nil
+	}
+
+	cond := condition{left: expr}
+	if strings.HasPrefix(expr, "!") {
+		cond = condition{left: strings.TrimSpace(expr[1:]), negate: true}
+		if cond.left == "" {
+			return cond, fmt.Errorf("invalid when %q, expected a value after !", expr)
+		}
+	}
+	return cond, nil
+}
+
+func hasOperator(value string) bool {
+	return strings.Contains(value, opEqual) || strings.Contains(value, opNotEqual)
+}
+
+// requiredVariable matches a variable without a default value
+var requiredVariable = regexp.MustCompile(`\{([^{}:]+)\}`)
+
+// resolveOperand resolves the variables in a value of a condition. Variables
+// without a default value are given an empty default, so a variable which is
+// not set is empty. Quotes around the value are removed.
+func resolveOperand(resolver Resolver, value string) (string, error) {
+	value = requiredVariable.ReplaceAllString(value, "{$1:}")
+	resolved, err := resolver.Resolve(value)
+	if err != nil {
+		return "", err
+	}
+	return unquote(strings.TrimSpace(resolved)), nil
+}
+
+func unquote(value string) string {
+	if len(value) < 2 {
+		return value
+	}
+	first, last := value[0], value[len(value)-1]
+	if first == last && (first == '"' || first == '\'') {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// EvaluateCondition resolves the variables in a when expression and returns
+// the result of the expression. An empty expression is always true.
+func EvaluateCondition(resolver Resolver, expr string) (bool, error) {
+	if strings.TrimSpace(expr) == "" {
+		return true, nil
+	}
+	cond, err := parseCondition(expr)
+	if err != nil {
+		return false, err
+	}
+	left, err := resolveOperand(resolver, cond.left)
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve when %q: %s", expr, err)
+	}
+
+	switch cond.operator {
+	case "":
+		return (left != "") != cond.negate, nil
+	default:
+		right, err := resolveOperand(resolver, cond.right)
+		if err != nil {
+			return false, fmt.Errorf("failed to resolve when %q: %s", expr, err)
+		}
+		return (left == right) == (cond.operator == opEqual), nil
+	}
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestEvaluateCondition(t *testing.T) {
+	resolver := newFakeResolver(map[string]string{
+		"{git.branch:}": "main",
+		"{env.CI:}":     "true",
+		"{env.UNSET:}":  "",
+	})
+
+	var testcases = []struct {
+		expr     string
+		expected bool
+	}{
+		{expr: "", expected: true},
+		{expr: "{git.branch} == main", expected: true},
+		{expr: "{git.branch}==main", expected: true},
+		{expr: "{git.branch} == 'main'", expected: true},
+		{expr: "{git.branch} == release", expected: false},
+		{expr: "{git.branch} != main", expected: false},
+		{expr: "{env.CI}", expected: true},
+		{expr: "{env.UNSET}", expected: false},
+		{expr: "!{env.CI}", expected: false},
+		{expr: "! {env.UNSET}", expected: true},
+		{expr: "{env.UNSET} == ''", expected: true},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.expr, func(t *testing.T) {
+			actual, err := EvaluateCondition(resolver, tc.expr)
+			assert.NilError(t, err)
+			assert.Check(t, is.Equal(tc.expected, actual))
+		})
+	}
+}
+
+func TestConditionalValidateWhen(t *testing.T) {
+	var testcases = []struct {
+		expr     string
+		expected string
+	}{
+		{expr: "== main", expected: "expected a value on both sides of =="},
+		{expr: "{git.branch} !=", expected: "expected a value on both sides of !="},
+		{expr: "{a} == b == c", expected: "only one comparison is supported"},
+		{expr: "!", expected: "expected a value after !"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.expr, func(t *testing.T) {
+			err := (&Conditional{When: tc.expr}).ValidateWhen()
+			assert.Check(t, is.ErrorContains(err, tc.expected))
+		})
+	}
+	assert.Check(t, (&Conditional{When: "{env.CI}"}).ValidateWhen())
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/dnephin/dobi/logging"
 	"github.com/dnephin/dobi/tasks/task"
 	log "github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v2"
 )
 
 // Config is a data object for a full config file
@@ -20,6 +21,22 @@ type Config struct {
 	Meta       *MetaConfig
 	Resources  map[string]Resource
 	WorkingDir string
+
+	// file is the name of the config file. Included files are relative to
+	// the directory of the file, and it is used in the position of errors.
+	file string
+	// parents are the files which include this config, used to detect cycles
+	parents []string
+	// positions of every value in the config file, by path
+	positions *positionTree
+	// sources are where each resource is defined, by resource name
+	sources map[string]source
+	// failed are the names of the resources which could not be loaded. They
+	// are not reported as missing when another resource refers to them.
+	failed map[string]bool
+	// plugins are the executables of the plugin resource types, by type. They
+	// are declared in the meta config of the file which includes this config.
+	plugins map[string]string
 }
 
 // NewConfig returns a new Config object
@@ -27,6 +44,8 @@ func NewConfig() *Config {
 	return &Config{
 		Resources: make(map[string]Resource),
 		Meta:      &MetaConfig{},
+		sources:   make(map[string]source),
+		failed:    make(map[string]bool),
 	}
 }
 
@@ -43,6 +62,12 @@ func (c *Config) contains(name string) bool {
 	return exists
 }
 
+// Source returns the path to the config file which defines the resource. The
+// path is empty when the config was not loaded from a file.
+func (c *Config) Source(name string) string {
+	return c.sources[name].file
+}
+
 // Sorted returns the list of resource names in alphabetical sort order
 func (c *Config) Sorted() []string {
 	names := []string{}
@@ -59,7 +84,7 @@ func Load(filename string) (*Config, error) {
 		return fmt.Errorf("failed to load config from %q: %s", filename, err)
 	}
 
-	config, err := loadConfig(filename)
+	config, err := loadConfig(filename, nil, nil)
 	if err != nil {
 		return nil, fmtError(err)
 	}
@@ -77,44 +102,126 @@ func Load(filename string) (*Config, error) {
 	return config, nil
 }
 
-func loadConfig(filename string) (*Config, error) {
-	data, err := ioutil.ReadFile(filename)
+// LoadAll loads a configuration from a filename like Load, but returns every
+// problem with the config instead of stopping at the first one. The config is
+// nil when the file can not be read or parsed.
+func LoadAll(filename string) (*Config, []error) {
+	absPath, err := filepath.Abs(filename)
 	if err != nil {
-		return nil, err
+		return nil, []error{err}
+	}
+	config, errs := loadConfigAll(filename, nil, nil)
+	if config == nil {
+		return nil, errs
 	}
-	config, err := LoadFromBytes(data)
+	config.WorkingDir = filepath.Dir(absPath)
+	config.FilePath = absPath
+	return config, append(errs, validateAll(config)...)
+}
+
+// loadConfig loads a config from a file, and returns the first error. The
+// parents are the files which include the file, and the plugins are the plugin
+// resource types declared by them.
+func loadConfig(
+	filename string,
+	parents []string,
+	plugins map[string]string,
+) (*Config, error) {
+	config, errs := loadConfigAll(filename, parents, plugins)
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	return config, nil
+}
+
+// loadConfigAll loads a config from a file like loadConfig, but returns every
+// resource which could be loaded along with every error. The config is nil
+// when the file can not be read or parsed.
+func loadConfigAll(
+	filename string,
+	parents []string,
+	plugins map[string]string,
+) (*Config, []error) {
+	data, err := ioutil.ReadFile(filename)
 	if err != nil {
-		return nil, err
+		return nil, []error{err}
 	}
+	values := make(map[string]map[string]interface{})
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, []error{err}
+	}
+	config := NewConfig()
+	config.parents = parents
+	config.plugins = plugins
+	config.setPositions(filename, data)
+	errs := config.loadValues(values)
 	logging.Log.WithFields(log.Fields{"filename": filename}).Debug("Configuration loaded")
-	return config, nil
+	return config, errs
 }
 
-// validate validates all the resources in the config
+// validate validates all the resources in the config, and returns the first
+// error
 func validate(config *Config) error {
-	for name, resource := range config.Resources {
-		path := pth.NewPath(name)
+	if errs := validateAll(config); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
 
-		if err := configtf.ValidateFields(path, resource); err != nil {
-			return err
-		}
-		if err := validateResourcesExist(path, config, resource.Dependencies()); err != nil {
-			return err
+// validateAll validates all the resources in the config, in sorted order, and
+// returns every error
+func validateAll(config *Config) []error {
+	errs := []error{}
+	for _, name := range config.Sorted() {
+		for _, err := range validateResource(config, name) {
+			errs = append(errs, config.positionError(name, err))
 		}
-		if err := resource.Validate(path, config); err != nil {
-			return err
+	}
+	if err := config.Meta.Validate(config); err != nil {
+		errs = append(errs, config.positionError(META, err))
+	}
+	return errs
+}
+
+// validateResource returns every error for the resource. The resource is only
+// validated when its fields are valid.
+func validateResource(config *Config, name string) []error {
+	resource := config.Resources[name]
+	path := pth.NewPath(name)
+	hooks := ResourceHooks(resource).All()
+
+	errs := []error{}
+	fieldsErr := configtf.ValidateFields(path, resource)
+	for _, err := range []error{
+		fieldsErr,
+		validateResourcesExist(path, config, resource.Dependencies()),
+		validateResourcesExist(path.Add("hooks"), config, hooks),
+	} {
+		if err != nil {
+			errs = append(errs, err)
 		}
 	}
-	return config.Meta.Validate(config)
+	if fieldsErr != nil {
+		return errs
+	}
+	if err := resource.Validate(path, config); err != nil {
+		errs = append(errs, err)
+	}
+	return errs
 }
 
 // validateResourcesExist checks that the list of resources is defined in the
-// config and returns an error if a resources is not defined.
+// config and returns an error if a resources is not defined. Empty names, and
+// the names of resources which could not be loaded, are ignored.
 func validateResourcesExist(path pth.Path, c *Config, names []string) error {
 	missing := []string{}
 	for _, name := range names {
 		resource := task.ParseName(name).Resource()
-		if _, ok := c.Resources[resource]; !ok {
+		if resource == "" {
+			// an empty required field is reported by configtf.ValidateFields
+			continue
+		}
+		if _, ok := c.Resources[resource]; !ok && !c.failed[resource] {
 			missing = append(missing, resource)
 		}
 	}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"testing"
 
 	"gotest.tools/v3/assert"
@@ -114,3 +115,345 @@ alias=aliasresource:
 	}
 	assert.Check(t, is.DeepEqual(expected, config, cmpConfigOpt))
 }
+
+func TestLoadAllReturnsEveryProblem(t *testing.T) {
+	dir := fs.NewDir(t, "load-all",
+		fs.WithFile("dobi.yaml", `
+job=one:
+    use: missing
+    freshness: bogus
+
+job=two:
+    depends: [nope]
+
+bogus=three: {}
+
+alias=four:
+    tasks: [one]
+    extra: field
+`))
+	defer dir.Remove()
+
+	config, errs := LoadAll(dir.Join("dobi.yaml"))
+	assert.Assert(t, config != nil)
+	for _, err := range errs {
+		_, ok := err.(*Error)
+		assert.Check(t, ok, "expected a position for %s", err)
+	}
+	messages := []string{}
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	file := dir.Join("dobi.yaml")
+	expected := []string{
+		file + ":13:5: four.extra: unexpected key",
+		file + `:9:1: three: invalid resource type "bogus"`,
+		file + `:4:5: one.freshness: failed validation: freshness must be one of "mtime" ` +
+			`or "hash", not "bogus"`,
+		file + ":2:1: one: missing dependencies: missing",
+		file + ":6:1: two.use: a value is required",
+		file + ":6:1: two: missing dependencies: nope",
+	}
+	assert.Check(t, is.DeepEqual(expected, messages))
+}
+
+func TestLoadAllDoesNotReportFailedResourcesAsMissing(t *testing.T) {
+	dir := fs.NewDir(t, "load-all",
+		fs.WithFile("dobi.yaml", `
+meta:
+    default: one
+    bogus: field
+
+job=one:
+    use: builder
+    freshness: [bogus]
+
+alias=two:
+    tasks: [one, three]
+`))
+	defer dir.Remove()
+
+	_, errs := LoadAll(dir.Join("dobi.yaml"))
+	messages := []string{}
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	file := dir.Join("dobi.yaml")
+	assert.Assert(t, is.Len(messages, 3), messages)
+	assert.Check(t, is.Contains(messages[0], `invalid "meta" config`))
+	assert.Check(t, is.Equal(
+		file+`:8:5: one.freshness: expected type "string" not "slice"`, messages[1]))
+	assert.Check(t, is.Equal(file+":10:1: two: missing dependencies: three", messages[2]))
+}
+
+func loadAllMessages(t *testing.T, content string) (string, []string) {
+	t.Helper()
+	dir := fs.NewDir(t, "load-all", fs.WithFile("dobi.yaml", content))
+	defer dir.Remove()
+
+	_, errs := LoadAll(dir.Join("dobi.yaml"))
+	messages := []string{}
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	return dir.Join("dobi.yaml"), messages
+}
+
+func TestLoadAllPositionOfKeysWithDots(t *testing.T) {
+	file, messages := loadAllMessages(t, `
+job=a:
+    use: builder
+    b: bogus
+
+job=a.b:
+    use: missing
+`)
+	expected := []string{
+		file + ":4:5: a.b: unexpected key",
+		file + ":6:1: a.b: missing dependencies: missing",
+		file + ":7:5: a.b.use: missing is not an image resource",
+	}
+	assert.Check(t, is.DeepEqual(expected, messages))
+}
+
+func TestLoadAllPositionWithAnchors(t *testing.T) {
+	file, messages := loadAllMessages(t, `
+job=base: &base
+    use: builder
+    freshness: bogus
+
+job=test:
+    <<: *base
+    command: go test
+`)
+	message := `freshness: failed validation: freshness must be one of "mtime" or "hash", ` +
+		`not "bogus"`
+	expected := []string{
+		file + ":4:5: base." + message,
+		file + ":2:1: base: missing dependencies: builder",
+		file + ":6:1: test." + message,
+		file + ":6:1: test: missing dependencies: builder",
+	}
+	assert.Check(t, is.DeepEqual(expected, messages))
+}
+
+func TestLoadAllWithDuplicateKeys(t *testing.T) {
+	file, messages := loadAllMessages(t, `
+job=test:
+    use: builder
+    use: other
+`)
+	expected := []string{
+		file + ":2:1: test: missing dependencies: other",
+		file + ":4:5: test.use: other is not an image resource",
+	}
+	assert.Check(t, is.DeepEqual(expected, messages))
+}
+
+func TestLoadAllReturnsEveryProblemFromIncludes(t *testing.T) {
+	dir := fs.NewDir(t, "load-all",
+		fs.WithFile("a.yaml", `
+image=img:
+    image: app
+    bogus: field
+
+job=other:
+    use: img
+    freshness: [bogus]
+`),
+		fs.WithFile("b.yaml", `
+mount=src:
+    path: [bogus]
+`),
+		fs.WithFile("dobi.yaml", `
+meta:
+    include: [a.yaml, b.yaml]
+
+job=top:
+    use: img
+    mounts: [src]
+    depends: [other]
+`))
+	defer dir.Remove()
+
+	_, errs := LoadAll(dir.Join("dobi.yaml"))
+	messages := []string{}
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	expected := []string{
+		dir.Join("a.yaml") + ":4:5: img.bogus: unexpected key",
+		dir.Join("a.yaml") + `:8:5: other.freshness: expected type "string" not "slice"`,
+		dir.Join("b.yaml") + `:3:5: src.path: expected type "string" not "slice"`,
+	}
+	assert.Check(t, is.DeepEqual(expected, messages))
+}
+
+func TestLoadAllWithInvalidYAML(t *testing.T) {
+	dir := fs.NewDir(t, "load-all", fs.WithFile("dobi.yaml", "job=one: [\n"))
+	defer dir.Remove()
+
+	config, errs := LoadAll(dir.Join("dobi.yaml"))
+	assert.Check(t, is.Nil(config))
+	assert.Check(t, is.Len(errs, 1))
+}
+
+func TestLoadErrorHasPositionInIncludedFile(t *testing.T) {
+	dir := fs.NewDir(t, "load-position",
+		fs.WithFile("shared.yaml", `
+alias=shared:
+    tasks: [missing]
+`))
+	defer dir.Remove()
+	dir2 := fs.NewDir(t, "load-position",
+		fs.WithFile("dobi.yaml", `
+meta:
+    include: [`+dir.Join("shared.yaml")+`]
+`))
+	defer dir2.Remove()
+
+	_, err := Load(dir2.Join("dobi.yaml"))
+	assert.Check(t, is.ErrorContains(err,
+		dir.Join("shared.yaml")+":2:1: shared: missing dependencies: missing"))
+}
+
+func TestLoadWithNamespacedIncludes(t *testing.T) {
+	team := `
+image=builder:
+    image: builder
+    dockerfile: Dockerfile
+
+mount=source:
+    bind: .
+    path: /go/src
+
+job=test:
+    use: builder
+    mounts: [source]
+    hooks:
+        after: [source:rm]
+`
+	dir := fs.NewDir(t, "load-namespace",
+		fs.WithFile("one.yaml", team),
+		fs.WithFile("two.yaml", team))
+	defer dir.Remove()
+	fs.Apply(t, dir, fs.WithFile("dobi.yaml", `
+meta:
+    include:
+        - {file: `+dir.Join("one.yaml")+`, namespace: one}
+        - {file: `+dir.Join("two.yaml")+`, namespace: two}
+
+alias=test:
+    tasks: [one.test, two.test:run]
+`))
+
+	config, err := Load(dir.Join("dobi.yaml"))
+	assert.NilError(t, err)
+	expected := []string{
+		"one.builder", "one.source", "one.test",
+		"test",
+		"two.builder", "two.source", "two.test",
+	}
+	assert.Check(t, is.DeepEqual(expected, config.Sorted()))
+
+	job, ok := config.Resources["two.test"].(*JobConfig)
+	assert.Assert(t, ok)
+	assert.Check(t, is.Equal("two.builder", job.Use))
+	assert.Check(t, is.DeepEqual([]string{"two.source"}, job.Mounts))
+	assert.Check(t, is.DeepEqual([]string{"two.source:rm"}, job.Hooks.After))
+	assert.Check(t, is.DeepEqual(
+		[]string{"two.builder", "two.source"}, job.Dependencies()))
+}
+
+func TestLoadErrorHasPositionInNamespacedInclude(t *testing.T) {
+	dir := fs.NewDir(t, "load-namespace",
+		fs.WithFile("shared.yaml", `
+alias=check:
+    tasks: [missing]
+`))
+	defer dir.Remove()
+	fs.Apply(t, dir, fs.WithFile("dobi.yaml", `
+meta:
+    include: [{file: `+dir.Join("shared.yaml")+`, namespace: shared}]
+
+alias=check:
+    tasks: [shared.check]
+`))
+
+	_, err := Load(dir.Join("dobi.yaml"))
+	assert.Check(t, is.ErrorContains(err,
+		"shared.yaml:2:1: shared.check: missing dependencies: shared.missing"))
+}
+
+func TestLoadWithNestedIncludesRelativeToIncludingFile(t *testing.T) {
+	dir := fs.NewDir(t, "load-nested",
+		fs.WithFile("dobi.yaml", `
+meta:
+    include: [ci/shared.yaml]
+
+alias=all:
+    tasks: [test, lint]
+`),
+		fs.WithDir("ci",
+			fs.WithFile("shared.yaml", `
+meta:
+    include: [lint/*.yaml]
+
+alias=test:
+    tasks: []
+`),
+			fs.WithDir("lint", fs.WithFile("lint.yaml", `
+alias=lint:
+    tasks: []
+`))))
+	defer dir.Remove()
+
+	config, err := Load(dir.Join("dobi.yaml"))
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual([]string{"all", "lint", "test"}, config.Sorted()))
+	assert.Check(t, is.Equal(dir.Join("dobi.yaml"), config.Source("all")))
+	assert.Check(t, is.Equal(dir.Join("ci", "shared.yaml"), config.Source("test")))
+	assert.Check(t, is.Equal(dir.Join("ci", "lint", "lint.yaml"), config.Source("lint")))
+}
+
+func TestLoadWithIncludeCycle(t *testing.T) {
+	dir := fs.NewDir(t, "load-cycle",
+		fs.WithFile("dobi.yaml", `
+meta:
+    include: [one.yaml]
+`),
+		fs.WithFile("one.yaml", `
+meta:
+    include: [two.yaml]
+`),
+		fs.WithFile("two.yaml", `
+meta:
+    include: [one.yaml]
+`))
+	defer dir.Remove()
+
+	_, err := Load(dir.Join("dobi.yaml"))
+	assert.Check(t, is.ErrorContains(err, fmt.Sprintf("include cycle: %s -> %s -> %s",
+		dir.Join("one.yaml"), dir.Join("two.yaml"), dir.Join("one.yaml"))))
+}
+
+func TestLoadWithExtendsOfIncludedResource(t *testing.T) {
+	dir := fs.NewDir(t, "load-extends",
+		fs.WithFile("shared.yaml", `
+job=base:
+    use: builder
+`),
+		fs.WithFile("dobi.yaml", `
+meta:
+    include: [shared.yaml]
+
+job=test:
+    extends: base
+`))
+	defer dir.Remove()
+
+	_, err := Load(dir.Join("dobi.yaml"))
+	assert.Check(t, is.ErrorContains(err,
+		`"base" is not a job resource in the same file, resources from an include `+
+			"can not be extended"))
+}
@@ -28,6 +28,8 @@ type EnvConfig struct {
 	// type: list of environment variables
 	Variables []string
 	Annotations
+	Conditional
+	Hookable
 }
 
 // Dependencies returns the list of job dependencies
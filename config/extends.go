@@ -0,0 +1,123 @@
+package config
+
+import (
+	"strings"
+
+	pth "github.com/dnephin/configtf/path"
+)
+
+const extendsField = "extends"
+
+// isExtendable returns true if resources of the type can extend another
+// resource
+func isExtendable(resType string) bool {
+	return isJobType(resType) || resType == "image"
+}
+
+// isSameType returns true if a resource of type other can be extended by a
+// resource of type resType
+func isSameType(resType, other string) bool {
+	return resType == other || (isJobType(resType) && isJobType(other))
+}
+
+// extendValue returns the value of the resource with the values of the resource
+// it extends, from the values of every resource in the same file. The parents
+// are the resources which are extending the resource, used to detect cycles.
+func extendValue(
+	values map[string]map[string]interface{},
+	name string,
+	parents []string,
+) (map[string]interface{}, error) {
+	value := values[name]
+	raw, ok := value[extendsField]
+	if !ok {
+		return value, nil
+	}
+	resType, _, err := parseResourceName(name)
+	if err != nil {
+		// the invalid name is reported when the resource is loaded
+		return value, nil
+	}
+	resPath := pth.NewPath(name)
+	path := resPath.Add(extendsField)
+	if !isExtendable(resType) {
+		return nil, pth.Errorf(path, "%s resources can not extend another resource", resType)
+	}
+	base, ok := raw.(string)
+	if !ok {
+		return nil, pth.Errorf(path, "must be the name of a resource, not %T", raw)
+	}
+	baseKey, ok := findResourceKey(values, base)
+	if baseType, _, _ := parseResourceName(baseKey); !ok || !isSameType(resType, baseType) {
+		return nil, pth.Errorf(path,
+			"%q is not a %s resource in the same file, resources from an include "+
+				"can not be extended", base, resType)
+	}
+	parents = append(append([]string{}, parents...), name)
+	if cycle := extendsCycle(parents, baseKey); cycle != "" {
+		return nil, pth.Errorf(path, "extends cycle: %s", cycle)
+	}
+
+	baseValue, err := extendValue(values, baseKey, parents)
+	if err != nil {
+		return nil, err
+	}
+	return mergeValues(baseValue, value), nil
+}
+
+// extendsCycle returns the cycle of resources when the base is one of the
+// parents which are extending it, or an empty string when there is no cycle
+func extendsCycle(parents []string, base string) string {
+	for i, parent := range parents {
+		if parent == base {
+			return strings.Join(append(append([]string{}, parents[i:]...), base), " -> ")
+		}
+	}
+	return ""
+}
+
+// findResourceKey returns the key in the config file of the resource with the
+// name
+func findResourceKey(values map[string]map[string]interface{}, name string) (string, bool) {
+	for key := range values {
+		if _, resName, err := parseResourceName(key); err == nil && resName == name {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// mergeValues returns the values of a resource which extends base. Values from
+// override replace the values from base, except for mappings, which are merged,
+// and lists, which are appended to the list from base.
+func mergeValues(base, override map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(override))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range override {
+		merged[key] = mergeValue(merged[key], value)
+	}
+	return merged
+}
+
+func mergeValue(base, override interface{}) interface{} {
+	switch value := override.(type) {
+	case []interface{}:
+		if list, ok := base.([]interface{}); ok {
+			return append(append([]interface{}{}, list...), value...)
+		}
+	case map[interface{}]interface{}:
+		if mapping, ok := base.(map[interface{}]interface{}); ok {
+			merged := make(map[interface{}]interface{}, len(mapping)+len(value))
+			for key, item := range mapping {
+				merged[key] = item
+			}
+			for key, item := range value {
+				merged[key] = mergeValue(merged[key], item)
+			}
+			return merged
+		}
+	}
+	return override
+}
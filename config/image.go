@@ -78,8 +78,37 @@ type ImageConfig struct {
 	NetworkMode string
 	// CacheFrom A list of images to use as the cache for a build.
 	CacheFrom []string
+	// Timeout The maximum time a build, pull, or push may run before it is
+	// cancelled. Defaults to the value of the ``--timeout`` flag.
+	// type: duration string
+	// example: ``10m``
+	Timeout Duration
+	// Retry Pull or push the image again when it fails. ``attempts`` is the
+	// maximum number of tries for each tag, and ``backoff`` is the time to
+	// wait before the first retry, which doubles after each retry. Errors which
+	// are not fixed by trying again, like a missing image or missing
+	// credentials for the registry, are not retried.
+	// type: mapping with keys ``attempts`` and ``backoff``
+	// example: ``{attempts: 3, backoff: 5s}``
+	Retry RetryConfig
+	// Freshness How the image decides if it needs to be built. With ``mtime``
+	// the last modified time of the files in ``context`` are compared to the
+	// image. With ``hash`` the content of the files in ``context`` is hashed,
+	// respecting ``.dockerignore``, and the image is rebuilt when the digest is
+	// different from the digest of the last build.
+	// default: ``mtime``
+	Freshness string `config:"validate"`
+	// Extends The name of another **image** in the same file. An **image**
+	// from an included file can not be extended. The **image** inherits every
+	// field of the other **image**, and the fields set on this **image**
+	// override them. Mappings like ``args`` are merged, and lists like
+	// ``tags`` are appended to the list of the other **image**.
+	// example: ``extends: base-image``
+	Extends string
 	Dependent
 	Annotations
+	Conditional
+	Hookable
 }
 
 // Validate checks that all fields have acceptable values
@@ -87,6 +116,9 @@ func (c *ImageConfig) Validate(path pth.Path, config *Config) *pth.Error {
 	if err := c.validateBuildOrPull(); err != nil {
 		return pth.Errorf(path, err.Error())
 	}
+	if err := c.Retry.Validate(); err != nil {
+		return pth.Errorf(path.Add("retry"), err.Error())
+	}
 	return nil
 }
 
@@ -121,6 +153,11 @@ func (c *ImageConfig) IsBuildable() bool {
 	return c.Context != "" && (c.Steps != "" || c.Dockerfile != "")
 }
 
+// ValidateFreshness validates the freshness mode
+func (c *ImageConfig) ValidateFreshness() error {
+	return validateFreshness(c.Freshness)
+}
+
 // ValidateImage validates the image field does not include a tag
 func (c *ImageConfig) ValidateImage() error {
 	_, tag := docker.ParseRepositoryTag(c.Image)
@@ -190,7 +227,8 @@ func NewImageConfig() *ImageConfig {
 type pullAction func(*time.Time) bool
 
 type pull struct {
-	action pullAction
+	action   pullAction
+	original string
 }
 
 func (p *pull) TransformConfig(raw reflect.Value) error {
@@ -214,12 +252,21 @@ func (p *pull) TransformConfig(raw reflect.Value) error {
 			}
 			p.action = pullAfter{duration: duration}.doPull
 		}
+		p.original = value
 	default:
 		return fmt.Errorf("must be a string, not %T", value)
 	}
 	return nil
 }
 
+// String returns the pull policy. The default policy is always.
+func (p *pull) String() string {
+	if !p.IsSet() {
+		return "always"
+	}
+	return p.original
+}
+
 func (p *pull) Required(lastPull *time.Time) bool {
 	if !p.IsSet() {
 		return true
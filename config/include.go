@@ -0,0 +1,216 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/dnephin/configtf"
+	"github.com/dnephin/dobi/tasks/task"
+)
+
+// Include is a config file, or a glob pattern of config files, to include
+type Include struct {
+	// File is the path or glob pattern of the config files
+	File string `config:"required"`
+	// Namespace is added to the name of every resource from the files. An
+	// empty namespace adds the resources with their own names.
+	Namespace string
+}
+
+// validateNamespace checks that the namespace can be part of a resource name
+func (i *Include) validateNamespace() error {
+	if strings.ContainsAny(i.Namespace, ":=.") {
+		return fmt.Errorf("invalid namespace %q, must not contain \":\", \"=\", or \".\"",
+			i.Namespace)
+	}
+	return nil
+}
+
+// Includes is a list of config files to include
+type Includes struct {
+	includes []Include
+}
+
+// JSONSchema returns the schema for a string, or a list of strings and include
+// mappings
+func (i *Includes) JSONSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"oneOf": []interface{}{
+			map[string]interface{}{"type": "string"},
+			map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"oneOf": []interface{}{
+						map[string]interface{}{"type": "string"},
+						structSchema(reflect.TypeOf(Include{})),
+					},
+				},
+			},
+		},
+	}
+}
+
+// TransformConfig from a raw value to a list of includes
+func (i *Includes) TransformConfig(raw reflect.Value) error {
+	if !raw.IsValid() {
+		return fmt.Errorf("must be a list of includes, was undefined")
+	}
+
+	switch value := raw.Interface().(type) {
+	case string:
+		i.includes = []Include{{File: value}}
+	case []interface{}:
+		for index, item := range value {
+			include, err := newInclude(index, item)
+			if err != nil {
+				return err
+			}
+			i.includes = append(i.includes, include)
+		}
+	default:
+		return fmt.Errorf("must be a string or list of includes, not %T", value)
+	}
+	return nil
+}
+
+func newInclude(index int, raw interface{}) (Include, error) {
+	include := Include{}
+	switch value := raw.(type) {
+	case string:
+		include.File = value
+	case map[interface{}]interface{}:
+		values := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			values[fmt.Sprintf("%v", key)] = item
+		}
+		path := fmt.Sprintf("%d", index)
+		if err := configtf.Transform(path, values, &include); err != nil {
+			return include, err
+		}
+		if include.File == "" {
+			return include, fmt.Errorf("item %d is missing a file", index)
+		}
+		if err := include.validateNamespace(); err != nil {
+			return include, fmt.Errorf("item %d has an %s", index, err)
+		}
+	default:
+		return include, fmt.Errorf("item %d must be a string or mapping, not %T", index, raw)
+	}
+	return include, nil
+}
+
+// Validate the glob patterns of the includes
+func (i *Includes) Validate() error {
+	_, err := i.Files("")
+	return err
+}
+
+// Files returns an Include for every file matched by the glob pattern of each
+// include. Relative patterns are relative to dir.
+func (i *Includes) Files(dir string) ([]Include, error) {
+	files := []Include{}
+	for _, include := range i.includes {
+		pattern := include.File
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			return files, err
+		}
+		for _, path := range paths {
+			files = append(files, Include{File: path, Namespace: include.Namespace})
+		}
+	}
+	return files, nil
+}
+
+// Empty returns true if there are no includes
+func (i *Includes) Empty() bool {
+	return len(i.includes) == 0
+}
+
+// checkIncludeCycle returns an error when the file is one of the parents, the
+// files which are including it
+func checkIncludeCycle(parents []string, filename string) error {
+	target, err := filepath.Abs(filename)
+	if err != nil {
+		return err
+	}
+	for i, parent := range parents {
+		if parent == "" {
+			continue
+		}
+		path, err := filepath.Abs(parent)
+		if err != nil {
+			return err
+		}
+		if path == target {
+			cycle := append(append([]string{}, parents[i:]...), filename)
+			return fmt.Errorf("include cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	return nil
+}
+
+// addIncluded adds the resources from an included config. The namespace is
+// added to the name of each resource, to every reference to another resource,
+// and to the names of the resources which could not be loaded.
+func (c *Config) addIncluded(namespace string, included *Config) error {
+	for _, name := range included.Sorted() {
+		resource := included.Resources[name]
+		qualifyResource(namespace, resource)
+		if err := c.add(task.QualifyName(namespace, name), resource); err != nil {
+			return err
+		}
+	}
+	for name := range included.failed {
+		c.failed[task.QualifyName(namespace, name)] = true
+	}
+	c.mergePositions(namespace, included)
+	return nil
+}
+
+// qualifier is implemented by resources with fields which refer to other
+// resources
+type qualifier interface {
+	qualifyReferences(namespace string)
+}
+
+// qualifyResource adds the namespace to every reference to another resource,
+// so that references resolve to resources included with the same namespace
+func qualifyResource(namespace string, resource Resource) {
+	if dependent, ok := resource.(interface{ qualifyDepends(string) }); ok {
+		dependent.qualifyDepends(namespace)
+	}
+	if hookable, ok := resource.(interface{ qualifyHooks(string) }); ok {
+		hookable.qualifyHooks(namespace)
+	}
+	if qualifier, ok := resource.(qualifier); ok {
+		qualifier.qualifyReferences(namespace)
+	}
+}
+
+// qualifyNames returns a copy of the list of task names, with the namespace
+// added to each name
+func qualifyNames(namespace string, names []string) []string {
+	if names == nil {
+		return nil
+	}
+	qualified := make([]string, len(names))
+	for i, name := range names {
+		qualified[i] = task.QualifyName(namespace, name)
+	}
+	return qualified
+}
+
+// qualifyKey adds the namespace to the resource name in a key from a config
+// file, or a path which starts with the key
+func qualifyKey(namespace, key string) string {
+	if namespace == "" || !strings.Contains(key, "=") {
+		return key
+	}
+	return strings.Replace(key, "=", "="+namespace+task.NamespaceSeparator, 1)
+}
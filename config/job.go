@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"strings"
 
 	"github.com/dnephin/configtf"
 	pth "github.com/dnephin/configtf/path"
+	"github.com/dnephin/dobi/tasks/task"
 	shlex "github.com/kballard/go-shellquote"
 	"golang.org/x/crypto/ssh/terminal"
 )
@@ -50,7 +52,9 @@ type JobConfig struct {
 	// Paths are relative to the ``dobi.yaml``
 	// type: list of file paths or glob patterns
 	Artifact PathGlobs
-	// Command The command to run in the container.
+	// Command The command to run in the container. Arguments given on the
+	// command line after ``--`` replace ``{args}`` in the command, or are
+	// appended to the command when it does not have ``{args}``.
 	// type: shell quoted string
 	// example: ``"bash -c 'echo something'"``
 	Command ShlexSlice
@@ -97,8 +101,50 @@ type JobConfig struct {
 	// Labels sets the labels of the running job container
 	// type: map of string keys to string values
 	Labels map[string]string
+	// Timeout The maximum time the container may run. When the timeout
+	// expires the container is killed and the **job** fails. Defaults to the
+	// value of the ``--timeout`` flag.
+	// type: duration string
+	// example: ``30m``
+	Timeout Duration
+	// Retry Run the **job** again when the container exits with a non-zero
+	// exit code. ``attempts`` is the maximum number of runs, ``backoff`` is the
+	// time to wait before the first retry, which doubles after each retry, and
+	// ``exit-codes`` limits the retries to specific exit codes.
+	// type: mapping with keys ``attempts``, ``backoff``, and ``exit-codes``
+	// example: ``{attempts: 3, backoff: 10s, exit-codes: [1]}``
+	Retry RetryConfig
+	// Freshness How the **job** decides if the ``artifact`` is stale. With
+	// ``mtime`` the last modified time of the files are compared. With ``hash``
+	// the content of the ``sources`` (or the ``mounts`` and the image ID of
+	// ``use`` when there are no ``sources``) is hashed, and the **job** is stale
+	// when the digest is different from the digest of the last run. The digest
+	// is stored in the ``.dobi`` directory.
+	// default: ``mtime``
+	Freshness string `config:"validate"`
+	// Matrix Run the **job** once for each combination of the values. The
+	// **job** is expanded into one task for each combination, named with the
+	// values in sorted order of the keys, like ``test[arch=amd64,go=1.21]``.
+	// The name of the **job** becomes an `alias`_ which runs all of them.
+	// The values are available as ``{matrix.<key>}`` in every field of the
+	// **job**, including ``env``, ``command``, and ``use``. Quote numeric
+	// values, so that ``"1.20"`` is not read as ``1.2``.
+	// type: mapping of keys to lists of values
+	// example: ``{go: ["1.21", "1.22"], arch: [amd64, arm64]}``
+	Matrix MatrixValues
+	// Extends The name of another **job** in the same file. A **job** from
+	// an included file can not be extended. The **job** inherits every field
+	// of the other **job**, and the fields set on this **job** override them.
+	// Mappings like ``labels`` are merged, and lists like ``env`` and
+	// ``mounts`` are appended to the list of the other **job**. A field which
+	// is a string in one **job** and a list in the other, like ``artifact``
+	// or ``sources``, is replaced instead of appended.
+	// example: ``extends: base-job``
+	Extends string
 	Dependent
 	Annotations
+	Conditional
+	Hookable
 }
 
 // Device is the defined structure to attach host devices to containers
@@ -113,6 +159,11 @@ func (c *JobConfig) Dependencies() []string {
 	return append([]string{c.Use}, append(c.Depends, c.Mounts...)...)
 }
 
+func (c *JobConfig) qualifyReferences(namespace string) {
+	c.Use = task.QualifyName(namespace, c.Use)
+	c.Mounts = qualifyNames(namespace, c.Mounts)
+}
+
 // Validate checks that all fields have acceptable values
 func (c *JobConfig) Validate(path pth.Path, config *Config) *pth.Error {
 	validators := []validator{
@@ -120,6 +171,7 @@ func (c *JobConfig) Validate(path pth.Path, config *Config) *pth.Error {
 		newValidator("mounts", func() error { return c.validateMounts(config) }),
 		newValidator("artifact", c.Artifact.Validate),
 		newValidator("sources", c.Sources.Validate),
+		newValidator("retry", c.Retry.Validate),
 	}
 	for _, validator := range validators {
 		if err := validator.validate(); err != nil {
@@ -129,11 +181,20 @@ func (c *JobConfig) Validate(path pth.Path, config *Config) *pth.Error {
 	return nil
 }
 
+// ValidateFreshness validates the freshness mode
+func (c *JobConfig) ValidateFreshness() error {
+	return validateFreshness(c.Freshness)
+}
+
 func (c *JobConfig) validateUse(config *Config) error {
 	err := fmt.Errorf("%s is not an image resource", c.Use)
 
 	res, ok := config.Resources[c.Use]
-	if !ok {
+	switch {
+	case !ok && config.failed[c.Use]:
+		// the resource which could not be loaded is already reported
+		return nil
+	case !ok:
 		return err
 	}
 
@@ -151,7 +212,10 @@ func (c *JobConfig) validateMounts(config *Config) error {
 		err := fmt.Errorf("%s is not a mount resource", mount)
 
 		res, ok := config.Resources[mount]
-		if !ok {
+		switch {
+		case !ok && config.failed[mount]:
+			continue
+		case !ok:
 			return err
 		}
 
@@ -217,6 +281,34 @@ func (s *ShlexSlice) Empty() bool {
 	return s.original == ""
 }
 
+// ArgsPlaceholder is replaced in the command of a job by the arguments given
+// on the command line after ``--``
+const ArgsPlaceholder = "{args}"
+
+// WithArgs returns the slice value with the args. An item which is only the
+// placeholder is replaced by all of the args, and the placeholder in any other
+// item is replaced by the args as a shell quoted string. The args are appended
+// when the value does not have a placeholder.
+func (s *ShlexSlice) WithArgs(args []string) []string {
+	switch {
+	case !strings.Contains(s.original, ArgsPlaceholder) && len(args) == 0:
+		return s.parsed
+	case !strings.Contains(s.original, ArgsPlaceholder):
+		return append(append([]string{}, s.parsed...), args...)
+	}
+	value := []string{}
+	for _, item := range s.parsed {
+		switch {
+		case item == ArgsPlaceholder:
+			value = append(value, args...)
+		default:
+			value = append(value,
+				strings.Replace(item, ArgsPlaceholder, shlex.Join(args...), -1))
+		}
+	}
+	return value
+}
+
 // TransformConfig is used to transform a string from a config file into a
 // sliced value, using shlex.
 func (s *ShlexSlice) TransformConfig(raw reflect.Value) error {
@@ -3,6 +3,7 @@ package config
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	pth "github.com/dnephin/configtf/path"
 	"gotest.tools/v3/assert"
@@ -55,6 +56,33 @@ func TestJobConfigRunFromConfig(t *testing.T) {
 	assert.Assert(t, is.DeepEqual(job.Entrypoint.Value(), []string{"bash", "-c"}))
 }
 
+func TestJobConfigFromConfigWithRetry(t *testing.T) {
+	values := map[string]interface{}{
+		"use": "image-res",
+		"retry": map[string]interface{}{
+			"attempts":   3,
+			"backoff":    "10s",
+			"exit-codes": []interface{}{1, 2},
+		},
+	}
+	res, err := jobFromConfig("foo", values)
+	assert.NilError(t, err)
+	job := res.(*JobConfig)
+	assert.Check(t, is.Equal(3, job.Retry.Attempts))
+	assert.Check(t, is.Equal(10*time.Second, job.Retry.Backoff.Value()))
+	assert.Check(t, is.DeepEqual([]int{1, 2}, job.Retry.ExitCodes))
+	assert.Check(t, job.Retry.RetriesExitCode(2))
+	assert.Check(t, !job.Retry.RetriesExitCode(3))
+}
+
+func TestJobConfigValidateRetry(t *testing.T) {
+	job := &JobConfig{Use: "image", Retry: RetryConfig{ExitCodes: []int{0}}}
+	conf := NewConfig()
+	conf.Resources["image"] = NewImageConfig()
+	err := job.Validate(pth.NewPath("job"), conf)
+	assert.Check(t, is.ErrorContains(err, "exit-codes must not include 0"))
+}
+
 func TestShlexSliceTransformConfig(t *testing.T) {
 	s := ShlexSlice{}
 	zero := reflect.Value{}
@@ -62,3 +90,41 @@ func TestShlexSliceTransformConfig(t *testing.T) {
 
 	assert.Check(t, is.ErrorContains(err, "must be a string"))
 }
+
+func TestShlexSliceWithArgs(t *testing.T) {
+	var testcases = []struct {
+		command  string
+		args     []string
+		expected []string
+	}{
+		{
+			command:  "go test ./...",
+			args:     []string{"-run", "TestFoo"},
+			expected: []string{"go", "test", "./...", "-run", "TestFoo"},
+		},
+		{
+			command:  "go test {args} ./...",
+			args:     []string{"-run", "TestFoo"},
+			expected: []string{"go", "test", "-run", "TestFoo", "./..."},
+		},
+		{
+			command:  "go test {args} ./...",
+			expected: []string{"go", "test", "./..."},
+		},
+		{
+			command:  "bash -c 'go test {args}'",
+			args:     []string{"-run", "Test Foo"},
+			expected: []string{"bash", "-c", "go test -run 'Test Foo'"},
+		},
+		{
+			command:  "go test",
+			expected: []string{"go", "test"},
+		},
+	}
+	for _, testcase := range testcases {
+		value := ShlexSlice{}
+		assert.NilError(t, value.TransformConfig(reflect.ValueOf(testcase.command)))
+		assert.Check(t, is.DeepEqual(testcase.expected, value.WithArgs(testcase.args)),
+			testcase.command)
+	}
+}
@@ -0,0 +1,191 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const matrixField = "matrix"
+
+// matrixVariable returns the template variable for a matrix key
+func matrixVariable(key string) string {
+	return "{matrix." + key + "}"
+}
+
+// MatrixValues are the values of the matrix for a job expanded from a matrix
+type MatrixValues map[string]string
+
+// JSONSchema returns the schema for the matrix in a config file, which is a
+// list of values for each key
+func (m *MatrixValues) JSONSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"additionalProperties": map[string]interface{}{
+			"type":     "array",
+			"minItems": 1,
+			"items": map[string]interface{}{
+				"type": []string{"string", "number", "boolean"},
+			},
+		},
+	}
+}
+
+// matrixValue is one value for each key of the matrix
+type matrixValue map[string]string
+
+// taskName returns the name of the task for the combination of values, with
+// the keys in sorted order. ex: test-unit[arch=amd64,go=1.21]
+func (m matrixValue) taskName(name string) string {
+	pairs := []string{}
+	for _, key := range sortedKeys(m) {
+		pairs = append(pairs, key+"="+m[key])
+	}
+	return fmt.Sprintf("%s[%s]", name, strings.Join(pairs, ","))
+}
+
+func sortedKeys(m matrixValue) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// parseMatrix converts the raw matrix value from a config file into a map of
+// keys to the list of values for the key
+func parseMatrix(raw interface{}) (map[string][]string, error) {
+	values, ok := raw.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("matrix must be a mapping of keys to lists, not %T", raw)
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("matrix must have at least one key")
+	}
+
+	matrix := make(map[string][]string, len(values))
+	for rawKey, rawList := range values {
+		key := fmt.Sprintf("%v", rawKey)
+		list, ok := rawList.([]interface{})
+		if !ok || len(list) == 0 {
+			return nil, fmt.Errorf("matrix key %q must be a non-empty list of values", key)
+		}
+		for _, item := range list {
+			switch item.(type) {
+			case string, int, float64, bool:
+				matrix[key] = append(matrix[key], fmt.Sprintf("%v", item))
+			default:
+				return nil, fmt.Errorf(
+					"matrix key %q has an invalid value %v, must be a scalar", key, item)
+			}
+		}
+	}
+	return matrix, nil
+}
+
+// combinations returns every combination of the values in the matrix
+func combinations(matrix map[string][]string) []matrixValue {
+	result := []matrixValue{{}}
+	keys := []string{}
+	for key := range matrix {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		next := []matrixValue{}
+		for _, combination := range result {
+			for _, value := range matrix[key] {
+				expanded := matrixValue{key: value}
+				for k, v := range combination {
+					expanded[k] = v
+				}
+				next = append(next, expanded)
+			}
+		}
+		result = next
+	}
+	return result
+}
+
+// substituteMatrix returns a copy of the raw config value with every matrix
+// variable replaced by its value
+func substituteMatrix(raw interface{}, combination matrixValue) interface{} {
+	switch value := raw.(type) {
+	case string:
+		for key, item := range combination {
+			value = strings.Replace(value, matrixVariable(key), item, -1)
+		}
+		return value
+	case []interface{}:
+		list := make([]interface{}, len(value))
+		for i, item := range value {
+			list[i] = substituteMatrix(item, combination)
+		}
+		return list
+	case map[interface{}]interface{}:
+		mapping := make(map[interface{}]interface{}, len(value))
+		for key, item := range value {
+			mapping[key] = substituteMatrix(item, combination)
+		}
+		return mapping
+	default:
+		return raw
+	}
+}
+
+// matrixResource is the name and config values of a resource expanded from a
+// matrix
+type matrixResource struct {
+	name   string
+	values map[string]interface{}
+}
+
+// expandMatrix expands the config values of a resource with a matrix into the
+// config values of one resource for each combination of the matrix. The matrix
+// variables are replaced in every string field, and the matrix field of each
+// expanded resource is set to the values of its combination.
+func expandMatrix(name string, values map[string]interface{}) ([]matrixResource, error) {
+	matrix, err := parseMatrix(values[matrixField])
+	if err != nil {
+		return nil, err
+	}
+
+	resources := []matrixResource{}
+	for _, combination := range combinations(matrix) {
+		expanded := make(map[string]interface{}, len(values))
+		for key, value := range values {
+			expanded[key] = substituteMatrix(value, combination)
+		}
+		fields := make(map[interface{}]interface{}, len(combination))
+		for key, value := range combination {
+			fields[key] = value
+		}
+		expanded[matrixField] = fields
+		resources = append(resources, matrixResource{
+			name:   combination.taskName(name),
+			values: expanded,
+		})
+	}
+	return resources, nil
+}
+
+// matrixAlias returns the config values for an alias which runs every resource
+// expanded from a matrix. The alias keeps the annotations of the resource.
+func matrixAlias(
+	values map[string]interface{},
+	resources []matrixResource,
+) map[string]interface{} {
+	tasks := []interface{}{}
+	for _, resource := range resources {
+		tasks = append(tasks, resource.name)
+	}
+	alias := map[string]interface{}{"tasks": tasks}
+	for _, key := range []string{"description", "annotations"} {
+		if value, ok := values[key]; ok {
+			alias[key] = value
+		}
+	}
+	return alias
+}
@@ -28,21 +28,38 @@ type MetaConfig struct {
 	Project string
 
 	// Include A list of dobi configuration files to include. Paths are
-	// relative to the current working directory. Includs can be partial
-	// configs that depend on resources in any of the other included files.
-	// type: list of file paths or glob patterns
-	Include PathGlobs
+	// relative to the directory of the file which includes them. Includs can
+	// be partial configs that depend on resources in any of the other included
+	// files. An included file may include other files, but it can not define
+	// any other meta config.
+	// An item can be a mapping with a ``file`` and a ``namespace``. The
+	// namespace is added to the name of every resource from the file, so
+	// ``job=test`` from the file is run as ``<namespace>.test``, and
+	// references to other resources in the file refer to resources in the
+	// same namespace.
+	// type: list of file paths or glob patterns, or mappings
+	// example: ``include: [common.yaml, {file: ci/shared.yaml, namespace: shared}]``
+	Include Includes
 
 	// ExecID A template value used as part of unique identifiers for image tags
 	// and container names. This field supports :doc:`variables`. This value can
 	// be overridden with the ``$DOBI_EXEC_ID`` environment variable.
 	// default: ``{user.name}``
 	ExecID string `config:"exec-id"`
+
+	// Plugins Resource types which are provided by an external executable,
+	// as a mapping of the type to the path of the executable. A relative
+	// path is relative to the directory of the ``dobi.yaml``, and a name
+	// without a path is found on the ``PATH`` when the plugin is run.
+	// Resources in included files can use the plugins.
+	// type: mapping ``type: executable``
+	// example: ``plugins: {helm: ./bin/dobi-resource-helm}``
+	Plugins map[string]string
 }
 
 // Validate the MetaConfig
 func (m *MetaConfig) Validate(config *Config) error {
-	if _, ok := config.Resources[m.Default]; m.Default != "" && !ok {
+	if _, ok := config.Resources[m.Default]; m.Default != "" && !ok && !config.failed[m.Default] {
 		return fmt.Errorf("undefined default resource: %s", m.Default)
 	}
 	if err := m.Include.Validate(); err != nil {
@@ -54,7 +71,7 @@ func (m *MetaConfig) Validate(config *Config) error {
 // IsZero returns true if the struct contains only zero values, except for
 // Includes which is ignored
 func (m *MetaConfig) IsZero() bool {
-	return m.Default == "" && m.Project == "" && m.ExecID == ""
+	return m.Default == "" && m.Project == "" && m.ExecID == "" && len(m.Plugins) == 0
 }
 
 // NewMetaConfig returns a new MetaConfig from config values
@@ -41,6 +41,8 @@ type MountConfig struct {
 	// default: ``0755`` *(for directories)*, ``0644`` *(for files)*
 	Mode int `config:"validate"`
 	Annotations
+	Conditional
+	Hookable
 }
 
 // Dependencies returns an empty list, Mount resources have no dependencies
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dnephin/configtf"
+	pth "github.com/dnephin/configtf/path"
+	"github.com/dnephin/dobi/tasks/task"
+)
+
+// pluginFields are the fields of a plugin resource which are handled by dobi.
+// All other fields are sent to the plugin.
+var pluginFields = map[string]bool{
+	"depends":     true,
+	"description": true,
+	"annotations": true,
+	"when":        true,
+	"hooks":       true,
+}
+
+// PluginConfig is a resource with a type provided by an external executable.
+// The type and the executable are declared in the plugins field of the meta
+// config. The executable is run by the tasks for the resource.
+type PluginConfig struct {
+	// Type is the resource type
+	Type string
+	// Executable is the path to the plugin executable
+	Executable string
+	// Values are the config fields which are sent to the plugin
+	Values map[string]interface{}
+
+	namespace string
+	Dependent
+	Annotations
+	Conditional
+	Hookable
+}
+
+func (c *PluginConfig) qualifyReferences(namespace string) {
+	if c.namespace == "" {
+		c.namespace = namespace
+		return
+	}
+	c.namespace = task.QualifyName(namespace, c.namespace)
+}
+
+// QualifyNames returns a copy of the task names returned by the plugin, with
+// the namespace of the file which defines the resource added to each name
+func (c *PluginConfig) QualifyNames(names []string) []string {
+	if c.namespace == "" {
+		return names
+	}
+	return qualifyNames(c.namespace, names)
+}
+
+// Validate the resource. The fields which are sent to the plugin are validated
+// by the plugin before the task runs.
+func (c *PluginConfig) Validate(path pth.Path, config *Config) *pth.Error {
+	return nil
+}
+
+func (c *PluginConfig) String() string {
+	return fmt.Sprintf("Run the %s plugin", c.Type)
+}
+
+// Resolve resolves variables in every string value of the config
+func (c *PluginConfig) Resolve(resolver Resolver) (Resource, error) {
+	conf := *c
+	values, err := resolveValue(resolver, c.Values)
+	if err != nil {
+		return &conf, err
+	}
+	conf.Values = values.(map[string]interface{})
+	return &conf, nil
+}
+
+func resolveValue(resolver Resolver, raw interface{}) (interface{}, error) {
+	switch value := raw.(type) {
+	case string:
+		return resolver.Resolve(value)
+	case []interface{}:
+		list := make([]interface{}, len(value))
+		for i, item := range value {
+			resolved, err := resolveValue(resolver, item)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = resolved
+		}
+		return list, nil
+	case map[string]interface{}:
+		mapping := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			resolved, err := resolveValue(resolver, item)
+			if err != nil {
+				return nil, err
+			}
+			mapping[key] = resolved
+		}
+		return mapping, nil
+	default:
+		return raw, nil
+	}
+}
+
+// jsonValue converts a value from a yaml document into a value which can be
+// encoded as JSON. Mappings from yaml have keys of type interface{}.
+func jsonValue(raw interface{}) interface{} {
+	switch value := raw.(type) {
+	case []interface{}:
+		list := make([]interface{}, len(value))
+		for i, item := range value {
+			list[i] = jsonValue(item)
+		}
+		return list
+	case map[interface{}]interface{}:
+		mapping := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			mapping[fmt.Sprintf("%v", key)] = jsonValue(item)
+		}
+		return mapping
+	default:
+		return raw
+	}
+}
+
+func pluginFromConfig(resType, executable string) resourceFactory {
+	return func(name string, values map[string]interface{}) (Resource, error) {
+		plugin := &PluginConfig{
+			Type:       resType,
+			Executable: executable,
+			Values:     make(map[string]interface{}),
+		}
+		common := make(map[string]interface{})
+		for key, value := range values {
+			if pluginFields[key] {
+				common[key] = value
+				continue
+			}
+			plugin.Values[key] = jsonValue(value)
+		}
+		return plugin, configtf.Transform(name, common, plugin)
+	}
+}
+
+// loadPlugins sets the executable for each plugin resource type declared in
+// the meta config. A relative path is relative to the directory of the config
+// file.
+func (c *Config) loadPlugins() error {
+	types := []string{}
+	for resType := range c.Meta.Plugins {
+		types = append(types, resType)
+	}
+	sort.Strings(types)
+
+	plugins := make(map[string]string, len(types))
+	for _, resType := range types {
+		executable := c.Meta.Plugins[resType]
+		switch {
+		case resType == META || resourceTypeRegistry[resType] != nil:
+			return fmt.Errorf("%q is a built-in resource type", resType)
+		case strings.ContainsAny(resType, "=:"):
+			return fmt.Errorf("invalid character in resource type %q", resType)
+		case executable == "":
+			return fmt.Errorf("resource type %q requires an executable", resType)
+		}
+		if strings.Contains(filepath.ToSlash(executable), "/") && !filepath.IsAbs(executable) {
+			executable = filepath.Join(filepath.Dir(c.file), executable)
+		}
+		plugins[resType] = executable
+	}
+	c.plugins = plugins
+	return nil
+}
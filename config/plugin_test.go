@@ -0,0 +1,82 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/dnephin/dobi/execenv"
+	"github.com/renstrom/dedent"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+func TestLoadFromBytesWithPlugin(t *testing.T) {
+	conf := dedent.Dedent(`
+		meta:
+		  plugins:
+		    helm: /usr/local/bin/dobi-resource-helm
+
+		helm=chart:
+		  release: "{project}"
+		  values: {replicas: 2}
+		  depends: [setup]
+	`)
+	config, err := LoadFromBytes([]byte(conf))
+	assert.NilError(t, err)
+
+	plugin, ok := config.Resources["chart"].(*PluginConfig)
+	assert.Assert(t, ok)
+	assert.Check(t, is.Equal("helm", plugin.Type))
+	assert.Check(t, is.Equal("/usr/local/bin/dobi-resource-helm", plugin.Executable))
+	expected := map[string]interface{}{
+		"release": "{project}",
+		"values":  map[string]interface{}{"replicas": 2},
+	}
+	assert.Check(t, is.DeepEqual(expected, plugin.Values))
+	assert.Check(t, is.DeepEqual([]string{"setup"}, plugin.Dependencies()))
+
+	resolved, err := plugin.Resolve(execenv.NewExecEnv("exec", "proj", "/work"))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal("proj", resolved.(*PluginConfig).Values["release"]))
+}
+
+func TestLoadFromBytesWithUnknownType(t *testing.T) {
+	_, err := LoadFromBytes([]byte("unknown=foo: {}\n"))
+	assert.Check(t, is.ErrorContains(err, `invalid resource type "unknown"`))
+}
+
+func TestLoadFromBytesWithBuiltinPluginType(t *testing.T) {
+	conf := dedent.Dedent(`
+		meta:
+		  plugins:
+		    job: dobi-resource-job
+	`)
+	_, err := LoadFromBytes([]byte(conf))
+	assert.Check(t, is.ErrorContains(err, `invalid plugins: "job" is a built-in resource type`))
+}
+
+func TestLoadWithPluginInInclude(t *testing.T) {
+	dir := fs.NewDir(t, "plugins",
+		fs.WithFile("dobi.yaml", dedent.Dedent(`
+			meta:
+			  plugins:
+			    helm: ./bin/dobi-resource-helm
+			  include:
+			    - {file: deploy/dobi.yaml, namespace: deploy}
+		`)),
+		fs.WithDir("deploy",
+			fs.WithFile("dobi.yaml", dedent.Dedent(`
+				helm=chart:
+				  release: web
+			`))))
+	defer dir.Remove()
+
+	config, err := Load(dir.Join("dobi.yaml"))
+	assert.NilError(t, err)
+	plugin, ok := config.Resources["deploy.chart"].(*PluginConfig)
+	assert.Assert(t, ok)
+	expected := filepath.Join(dir.Path(), "bin", "dobi-resource-helm")
+	assert.Check(t, is.Equal(expected, plugin.Executable))
+	assert.Check(t, is.DeepEqual([]string{"deploy.db"}, plugin.QualifyNames([]string{"db"})))
+}
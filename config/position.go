@@ -0,0 +1,186 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	pth "github.com/dnephin/configtf/path"
+	"github.com/dnephin/dobi/tasks/task"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// Position is the location of a value in a config file
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// Error is a problem with a value in a config file
+type Error struct {
+	Position Position
+	// Path is the resource name followed by the names of the fields
+	Path    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s: %s", e.Position, e.Path, e.Message)
+}
+
+// source is where a resource is defined
+type source struct {
+	// file is the config file which defines the resource
+	file string
+	// key is the key of the resource in the file
+	key string
+}
+
+// positionTree is the position of a value in a config file, and the positions
+// of the values it contains by key or list index. Positions are stored by path
+// segment because keys, like the names of resources and matrix values, may
+// contain dots.
+type positionTree struct {
+	position Position
+	children map[string]*positionTree
+}
+
+func newPositionTree() *positionTree {
+	return &positionTree{children: make(map[string]*positionTree)}
+}
+
+// child returns the tree for the segment, and adds it if it does not exist
+func (t *positionTree) child(segment string) *positionTree {
+	child, ok := t.children[segment]
+	if !ok {
+		child = newPositionTree()
+		t.children[segment] = child
+	}
+	return child
+}
+
+// lookup returns the position of the value at the path, or of the closest
+// parent of the value which has a position. It returns false when no value in
+// the path has a position.
+func (t *positionTree) lookup(path []string) (Position, bool) {
+	var position Position
+	found := false
+	node := t
+	for _, segment := range path {
+		if node = node.children[segment]; node == nil {
+			break
+		}
+		position, found = node.position, true
+	}
+	return position, found
+}
+
+// setPositions records the position of every value in the config file
+func (c *Config) setPositions(filename string, data []byte) {
+	c.file = filename
+	c.positions = parsePositions(filename, data)
+}
+
+// mergePositions adds the sources and positions of the resources from an
+// included config, so that errors for those resources have the position in the
+// included file
+func (c *Config) mergePositions(namespace string, included *Config) {
+	for name, source := range included.sources {
+		source.key = qualifyKey(namespace, source.key)
+		c.sources[task.QualifyName(namespace, name)] = source
+	}
+	if c.positions == nil || included.positions == nil {
+		return
+	}
+	for key, tree := range included.positions.children {
+		c.positions.children[qualifyKey(namespace, key)] = tree
+	}
+}
+
+// parsePositions returns the position of every key and list item in the
+// document. The document is decoded by yaml.v2, which does not record
+// positions, so a document which can not be parsed returns no positions and
+// the error is reported when it is decoded. Values merged from an anchor have
+// the position of the key they are merged into. When a key is repeated
+// yaml.v2 uses the last value, which has the position of the last key.
+func parsePositions(filename string, data []byte) *positionTree {
+	positions := newPositionTree()
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 {
+		return positions
+	}
+	addPositions(filename, doc.Content[0], positions)
+	return positions
+}
+
+func addPositions(filename string, node *yamlv3.Node, positions *positionTree) {
+	add := func(segment string, key *yamlv3.Node, value *yamlv3.Node) {
+		child := positions.child(segment)
+		child.position = Position{File: filename, Line: key.Line, Column: key.Column}
+		addPositions(filename, value, child)
+	}
+
+	switch node.Kind {
+	case yamlv3.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			add(node.Content[i].Value, node.Content[i], node.Content[i+1])
+		}
+	case yamlv3.SequenceNode:
+		for i, item := range node.Content {
+			add(strconv.Itoa(i), item, item)
+		}
+	}
+}
+
+// positionError returns an Error with the position of the value at the path
+// of err. The path is the path of a *pth.Error, or the resource name when err
+// is not a *pth.Error. The error is returned unchanged when the config was not
+// loaded from a file.
+func (c *Config) positionError(resource string, err error) error {
+	if c.positions == nil {
+		return err
+	}
+	path := []string{resource}
+	message := err.Error()
+	if cause := errors.Unwrap(err); cause != nil {
+		message = cause.Error()
+	}
+	var pathErr *pth.Error
+	if errors.As(err, &pathErr) {
+		errPath := pathErr.Path()
+		path = errPath.Path()
+		message = strings.TrimPrefix(
+			pathErr.Error(), fmt.Sprintf("Error at %s: ", errPath.String()))
+	}
+
+	// paths from validation start with the resource name, and paths from
+	// transforming the config start with the key in the file
+	key, name := path[0], path[0]
+	if source, ok := c.sources[name]; ok {
+		key = source.key
+	}
+	if _, resName, err := parseResourceName(key); err == nil {
+		name = resName
+	}
+
+	return &Error{
+		Position: c.position(append([]string{key}, path[1:]...)),
+		Path:     strings.Join(append([]string{name}, path[1:]...), "."),
+		Message:  message,
+	}
+}
+
+// position returns the position of the value at the path, or of the closest
+// parent of the value which has a position
+func (c *Config) position(path []string) Position {
+	if position, ok := c.positions.lookup(path); ok {
+		return position
+	}
+	return Position{File: c.file}
+}
@@ -74,6 +74,96 @@ func (d *Dependent) Dependencies() []string {
 	return d.Depends
 }
 
+func (d *Dependent) qualifyDepends(namespace string) {
+	d.Depends = qualifyNames(namespace, d.Depends)
+}
+
+// Conditional can be used to provide part of the Resource interface
+type Conditional struct {
+	// When An expression which must be true for the task to run. The
+	// expression compares two values with ``==`` or ``!=``, or checks that a
+	// value is not empty, and a leading ``!`` checks that a value is empty.
+	// Variables are resolved before the values are compared, and a variable
+	// which is not set is empty. The expression is evaluated immediately
+	// before the task would run. When it is false the task is skipped, but
+	// the dependencies of the task have already run, and the tasks which
+	// depend on it still run. Set ``when`` on a dependency to skip it too.
+	// example: ``when: "{git.branch} == main"``
+	When string `config:"validate"`
+}
+
+// ConditionalResource is implemented by resources which have a when condition
+type ConditionalResource interface {
+	Condition() string
+}
+
+// Condition returns the when expression
+func (c *Conditional) Condition() string {
+	return c.When
+}
+
+// ValidateWhen checks the syntax of the when expression
+func (c *Conditional) ValidateWhen() error {
+	_, err := parseCondition(c.When)
+	return err
+}
+
+// HooksConfig is the list of tasks to run around the task of a resource
+type HooksConfig struct {
+	// Before Tasks to run before the task
+	Before []string
+	// After Tasks to run after the task succeeds
+	After []string
+	// OnFailure Tasks to run when the task, or one of its hooks, fails
+	OnFailure []string
+}
+
+// All returns the names of every hook task
+func (h HooksConfig) All() []string {
+	all := append([]string{}, h.Before...)
+	all = append(all, h.After...)
+	return append(all, h.OnFailure...)
+}
+
+// Hookable can be used to provide part of the Resource interface
+type Hookable struct {
+	// Hooks Tasks to run around the task of the resource. ``before`` tasks are
+	// run immediately before the task, ``after`` tasks are run when the task
+	// succeeds, and ``on-failure`` tasks are run when the task, or one of its
+	// hooks, fails. Hooks are run every time the task runs, even when the task
+	// is fresh.
+	// type: mapping with keys ``before``, ``after``, and ``on-failure``
+	// example: ``{before: [db:up], on-failure: [db:logs], after: [db:down]}``
+	Hooks HooksConfig
+}
+
+// HookedResource is implemented by resources which have hooks
+type HookedResource interface {
+	LifecycleHooks() HooksConfig
+}
+
+// ResourceHooks returns the hooks of the resource, or no hooks if the resource
+// does not implement HookedResource
+func ResourceHooks(resource Resource) HooksConfig {
+	if hooked, ok := resource.(HookedResource); ok {
+		return hooked.LifecycleHooks()
+	}
+	return HooksConfig{}
+}
+
+// LifecycleHooks returns the hooks of the resource
+func (h *Hookable) LifecycleHooks() HooksConfig {
+	return h.Hooks
+}
+
+func (h *Hookable) qualifyHooks(namespace string) {
+	h.Hooks = HooksConfig{
+		Before:    qualifyNames(namespace, h.Hooks.Before),
+		After:     qualifyNames(namespace, h.Hooks.After),
+		OnFailure: qualifyNames(namespace, h.Hooks.OnFailure),
+	}
+}
+
 // Resolver is an interface for a type that returns values for variables
 type Resolver interface {
 	Resolve(tmpl string) (string, error)
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"reflect"
+	"regexp"
+	"sort"
+
+	"github.com/dnephin/configtf"
+)
+
+// schemaProvider is implemented by config field types which transform the
+// value from the config file, and accept values which are not a string
+type schemaProvider interface {
+	JSONSchema() map[string]interface{}
+}
+
+var (
+	schemaProviderType = reflect.TypeOf((*schemaProvider)(nil)).Elem()
+	transformerType    = reflect.TypeOf((*interface {
+		TransformConfig(reflect.Value) error
+	})(nil)).Elem()
+)
+
+// JSONSchema returns a JSON Schema for a config file. The schema includes the
+// meta section, and every resource type in the registry. Plugin resource types
+// are accepted as any mapping.
+func JSONSchema() map[string]interface{} {
+	definitions := make(map[string]interface{})
+	resources := make(map[string]interface{})
+	for _, resType := range resourceTypes() {
+		resource, _ := resourceTypeRegistry[resType]("schema", map[string]interface{}{})
+		definitions[resType] = structSchema(reflect.TypeOf(resource).Elem())
+		pattern := "^" + regexp.QuoteMeta(resType) + "=[^:]+$"
+		resources[pattern] = map[string]interface{}{"$ref": "#/definitions/" + resType}
+	}
+	resources["^[^=]+=[^:]+$"] = map[string]interface{}{"type": "object"}
+
+	meta := map[string]interface{}{META: structSchema(reflect.TypeOf(MetaConfig{}))}
+	return map[string]interface{}{
+		"$schema":              "http://json-schema.org/draft-07/schema#",
+		"title":                "dobi config",
+		"type":                 "object",
+		"properties":           meta,
+		"patternProperties":    resources,
+		"additionalProperties": false,
+		"definitions":          definitions,
+	}
+}
+
+// resourceTypes returns the names of the registered resource types in sorted
+// order
+func resourceTypes() []string {
+	types := []string{}
+	for resType := range resourceTypeRegistry {
+		types = append(types, resType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// structSchema returns the schema for a config struct. Fields from embedded
+// structs are included, and field names are converted in the same way as
+// configtf.Transform.
+func structSchema(structType reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := []string{}
+	addStructFields(structType, properties, &required)
+
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+func addStructFields(
+	structType reflect.Type,
+	properties map[string]interface{},
+	required *[]string,
+) {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		switch {
+		case field.Anonymous:
+			addStructFields(field.Type, properties, required)
+			continue
+		case field.PkgPath != "":
+			continue
+		}
+
+		tags := configtf.NewFieldTags(field.Name, field.Tag.Get(configtf.StructTagKey))
+		properties[tags.Name] = typeSchema(field.Type)
+		if tags.IsRequired {
+			*required = append(*required, tags.Name)
+		}
+	}
+}
+
+// nolint: gocyclo
+func typeSchema(fieldType reflect.Type) map[string]interface{} {
+	ptrType := reflect.PtrTo(fieldType)
+	switch {
+	case ptrType.Implements(schemaProviderType):
+		return reflect.New(fieldType).Interface().(schemaProvider).JSONSchema()
+	case ptrType.Implements(transformerType):
+		return map[string]interface{}{"type": "string"}
+	}
+
+	switch fieldType.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": typeSchema(fieldType.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": typeSchema(fieldType.Elem()),
+		}
+	case reflect.Struct:
+		return structSchema(fieldType)
+	default:
+		return map[string]interface{}{}
+	}
+}
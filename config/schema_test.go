@@ -0,0 +1,36 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestJSONSchema(t *testing.T) {
+	schema := JSONSchema()
+
+	definitions := schema["definitions"].(map[string]interface{})
+	for _, resType := range []string{"alias", "compose", "env", "image", "job", "mount"} {
+		assert.Check(t, definitions[resType] != nil, "missing %s", resType)
+	}
+
+	job := definitions["job"].(map[string]interface{})
+	assert.Check(t, is.DeepEqual([]string{"use"}, job["required"]))
+	properties := job["properties"].(map[string]interface{})
+	for _, field := range []string{"use", "command", "depends", "when", "hooks", "matrix"} {
+		assert.Check(t, properties[field] != nil, "missing field %s", field)
+	}
+	assert.Check(t, is.DeepEqual(map[string]interface{}{"type": "boolean"},
+		properties["provide-docker"]))
+	assert.Check(t, is.DeepEqual(map[string]interface{}{"type": "string"},
+		properties["timeout"]))
+
+	patterns := schema["patternProperties"].(map[string]interface{})
+	assert.Check(t, is.DeepEqual(
+		map[string]interface{}{"$ref": "#/definitions/job"}, patterns["^job=[^:]+$"]))
+
+	_, err := json.Marshal(schema)
+	assert.NilError(t, err)
+}
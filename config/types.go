@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"strings"
+	"time"
 )
 
 // PathGlobs is a list of path globs
@@ -12,6 +13,19 @@ type PathGlobs struct {
 	globs []string
 }
 
+// JSONSchema returns the schema for a string or a list of strings
+func (p *PathGlobs) JSONSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"oneOf": []interface{}{
+			map[string]interface{}{"type": "string"},
+			map[string]interface{}{
+				"type":  "array",
+				"items": map[string]interface{}{"type": "string"},
+			},
+		},
+	}
+}
+
 // Validate the globs
 func (p *PathGlobs) Validate() error {
 	_, err := p.all()
@@ -84,6 +98,108 @@ func (p *PathGlobs) NoMatches() bool {
 	return !p.Empty() && len(p.Paths()) == 0
 }
 
+// Duration is a length of time, configured as a string with a unit suffix
+type Duration struct {
+	original string
+	value    time.Duration
+}
+
+// TransformConfig from a raw value to a duration
+func (d *Duration) TransformConfig(raw reflect.Value) error {
+	if !raw.IsValid() {
+		return fmt.Errorf("must be a duration, was undefined")
+	}
+
+	switch value := raw.Interface().(type) {
+	case string:
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q: %s", value, err)
+		}
+		if duration < 0 {
+			return fmt.Errorf("duration %q must not be negative", value)
+		}
+		d.original = value
+		d.value = duration
+	default:
+		return fmt.Errorf("must be a duration string like '10m', not %T", value)
+	}
+	return nil
+}
+
+// Value returns the duration
+func (d *Duration) Value() time.Duration {
+	return d.value
+}
+
+// Empty returns true if no duration was set
+func (d *Duration) Empty() bool {
+	return d.value == 0
+}
+
+func (d *Duration) String() string {
+	return d.original
+}
+
+// Freshness modes used to decide if a task is stale
+const (
+	// FreshnessModified compares the last modified time of files
+	FreshnessModified = "mtime"
+	// FreshnessHash compares a digest of the content of files to the digest
+	// from the last run
+	FreshnessHash = "hash"
+)
+
+func validateFreshness(value string) error {
+	switch value {
+	case "", FreshnessModified, FreshnessHash:
+		return nil
+	default:
+		return fmt.Errorf("freshness must be one of %q or %q, not %q",
+			FreshnessModified, FreshnessHash, value)
+	}
+}
+
+// RetryConfig is the policy used to run a task again after it fails
+type RetryConfig struct {
+	// Attempts The maximum number of times to run the task. The task is not
+	// retried when it is less than 2.
+	Attempts int
+	// Backoff The time to wait before the first retry. The wait is doubled
+	// after each retry.
+	Backoff Duration
+	// ExitCodes The exit codes which are retried. Any non-zero exit code is
+	// retried when the list is empty.
+	ExitCodes []int
+}
+
+// Validate the retry policy
+func (r *RetryConfig) Validate() error {
+	if r.Attempts < 0 {
+		return fmt.Errorf("attempts must not be negative, was %d", r.Attempts)
+	}
+	for _, code := range r.ExitCodes {
+		if code == 0 {
+			return fmt.Errorf("exit-codes must not include 0")
+		}
+	}
+	return nil
+}
+
+// RetriesExitCode returns true if the policy retries a task that exited
+// with code
+func (r *RetryConfig) RetriesExitCode(code int) bool {
+	if len(r.ExitCodes) == 0 {
+		return code != 0
+	}
+	for _, retryCode := range r.ExitCodes {
+		if retryCode == code {
+			return true
+		}
+	}
+	return false
+}
+
 type validator struct {
 	name     string
 	validate func() error
@@ -3,8 +3,10 @@ package config
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
 )
 
 func TestPathGlobsTransformConfigFromSlice(t *testing.T) {
@@ -15,3 +17,27 @@ func TestPathGlobsTransformConfigFromSlice(t *testing.T) {
 	assert.NilError(t, err)
 	assert.DeepEqual(t, []string{"one", "two", "three"}, globs.globs)
 }
+
+func TestDurationTransformConfig(t *testing.T) {
+	duration := Duration{}
+	err := duration.TransformConfig(reflect.ValueOf("1h30m"))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(90*time.Minute, duration.Value()))
+	assert.Check(t, is.Equal("1h30m", duration.String()))
+}
+
+func TestDurationTransformConfigInvalid(t *testing.T) {
+	duration := Duration{}
+	err := duration.TransformConfig(reflect.ValueOf("ten minutes"))
+	assert.Check(t, is.ErrorContains(err, `invalid duration "ten minutes"`))
+
+	err = duration.TransformConfig(reflect.ValueOf(10))
+	assert.Check(t, is.Error(err, "must be a duration string like '10m', not int"))
+}
+
+func TestValidateFreshness(t *testing.T) {
+	assert.Check(t, validateFreshness(""))
+	assert.Check(t, validateFreshness("hash"))
+	assert.Check(t, is.Error(validateFreshness("content"),
+		`freshness must be one of "mtime" or "hash", not "content"`))
+}
@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"path/filepath"
+	"sort"
 	"strings"
 
 	yaml "gopkg.in/yaml.v2"
@@ -25,8 +27,13 @@ var (
 
 type resourceFactory func(string, map[string]interface{}) (Resource, error)
 
+// IsReservedName returns true if name can not be used as the name of a resource
+func IsReservedName(name string) bool {
+	return reservedNames[name]
+}
+
 func validateName(name string) error {
-	if _, reserved := reservedNames[name]; reserved {
+	if IsReservedName(name) {
 		return fmt.Errorf(
 			"%q is reserved, please use a different resource name", name)
 	}
@@ -44,57 +51,166 @@ func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		// TODO: better error message on unmarshal failure
 		return err
 	}
+	if errs := c.loadValues(values); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
 
+// loadValues loads the meta config and every resource from the values, and
+// returns an error for the meta config and each resource which could not be
+// loaded. Resources are loaded in sorted order, so the errors are in a
+// consistent order. The names of the resources which could not be loaded are
+// recorded, so they are not reported as missing by validation.
+func (c *Config) loadValues(values map[string]map[string]interface{}) []error {
+	errs := []error{}
 	if value, ok := values[META]; ok {
-		if err := c.loadMeta(value); err != nil {
-			return err
-		}
+		errs = append(errs, c.loadMeta(value)...)
 		delete(values, META)
 	}
-	for name, value := range values {
-		resType, resName, err := parseResourceName(name)
+
+	names := []string{}
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// every resource is extended before any are loaded, because loading a
+	// resource removes the fields from its values
+	extended := make(map[string]map[string]interface{}, len(names))
+	extendErrs := make(map[string]error, len(names))
+	for _, name := range names {
+		extended[name], extendErrs[name] = extendValue(values, name, nil)
+	}
+
+	for _, name := range names {
+		err := extendErrs[name]
+		if err == nil {
+			err = c.loadValue(name, extended[name])
+		}
 		if err != nil {
-			return err
+			c.addFailed(name)
+			errs = append(errs, c.positionError(name, err))
 		}
+	}
+	return errs
+}
+
+// addFailed records the name of a resource which could not be loaded
+func (c *Config) addFailed(name string) {
+	if _, resName, err := parseResourceName(name); err == nil {
+		c.failed[resName] = true
+	}
+}
+
+func (c *Config) loadValue(name string, value map[string]interface{}) error {
+	resType, resName, err := parseResourceName(name)
+	if err != nil {
+		return err
+	}
+	if err = validateName(resName); err != nil {
+		return err
+	}
+	c.sources[resName] = source{file: c.file, key: name}
+	if _, ok := value[matrixField]; ok && isJobType(resType) {
+		return c.addMatrix(name, resType, resName, value)
+	}
+	return c.addResource(name, resType, resName, value)
+}
 
-		if err = validateName(resName); err != nil {
+func isJobType(resType string) bool {
+	return resType == "job" || resType == "run"
+}
+
+func (c *Config) addResource(
+	name, resType, resName string,
+	value map[string]interface{},
+) error {
+	resource, err := c.unmarshalResource(name, resType, value)
+	if err != nil {
+		return fmt.Errorf("invalid config for resource %q:\n%w", name, err)
+	}
+	return c.add(resName, resource)
+}
+
+// addMatrix adds a resource for each combination of the matrix, and an alias
+// with the name of the resource which runs all of them
+func (c *Config) addMatrix(
+	name, resType, resName string,
+	value map[string]interface{},
+) error {
+	resources, err := expandMatrix(resName, value)
+	if err != nil {
+		return fmt.Errorf("invalid config for resource %q:\n%s", name, err)
+	}
+	for _, resource := range resources {
+		if err := validateName(resource.name); err != nil {
 			return err
 		}
-
-		resource, err := unmarshalResource(name, resType, value)
+		c.sources[resource.name] = source{file: c.file, key: name}
+		fullName := resType + "=" + resource.name
+		err := c.addResource(fullName, resType, resource.name, resource.values)
 		if err != nil {
-			return fmt.Errorf("invalid config for resource %q:\n%s", name, err)
-		}
-		if err := c.add(resName, resource); err != nil {
 			return err
 		}
 	}
-	return nil
+	return c.addResource(name, "alias", resName, matrixAlias(value, resources))
 }
 
-func (c *Config) loadMeta(value map[string]interface{}) error {
+func (c *Config) loadMeta(value map[string]interface{}) []error {
 	var err error
 	c.Meta, err = NewMetaConfig(META, value)
 	if err != nil {
-		return fmt.Errorf("invalid \"meta\" config: %s", err)
+		return []error{fmt.Errorf("invalid \"meta\" config: %s", err)}
+	}
+	if err := c.loadPlugins(); err != nil {
+		return []error{fmt.Errorf("invalid plugins: %s", err)}
 	}
 
-	// TODO: prevent infinite recursive includes
-	for _, include := range c.Meta.Include.Paths() {
-		config, err := loadConfig(include)
-		if err != nil {
-			return fmt.Errorf("error including %q: %s", include, err)
+	includes, err := c.Meta.Include.Files(filepath.Dir(c.file))
+	if err != nil {
+		return []error{fmt.Errorf("invalid include: %s", err)}
+	}
+	return c.loadIncludes(includes)
+}
+
+// loadIncludes loads every included file, and returns the errors from all of
+// them. The resources from an included file are added even when some of them
+// could not be loaded, and the ones which could not be loaded are recorded as
+// failed.
+func (c *Config) loadIncludes(includes []Include) []error {
+	parents := append(append([]string{}, c.parents...), c.file)
+	errs := []error{}
+	for _, include := range includes {
+		if err := checkIncludeCycle(parents, include.File); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		config, includeErrs := loadConfigAll(include.File, parents, c.plugins)
+		for _, err := range includeErrs {
+			errs = append(errs, includeError(include.File, err))
+		}
+		if config == nil {
+			continue
 		}
 		if !config.Meta.IsZero() {
-			return fmt.Errorf("include %q can not define meta config", include)
+			errs = append(errs, fmt.Errorf(
+				"include %q can not define meta config other than include", include.File))
 		}
-		for name, resource := range config.Resources {
-			if err := c.add(name, resource); err != nil {
-				return fmt.Errorf("error including %q: %s", include, err)
-			}
+		if err := c.addIncluded(include.Namespace, config); err != nil {
+			errs = append(errs, includeError(include.File, err))
 		}
 	}
-	return nil
+	return errs
+}
+
+// includeError returns the error from loading an included file. An error with
+// a position already has the name of the included file.
+func includeError(filename string, err error) error {
+	if _, ok := err.(*Error); ok {
+		return err
+	}
+	return fmt.Errorf("error including %q: %s", filename, err)
 }
 
 func parseResourceName(value string) (string, string, error) {
@@ -112,10 +228,17 @@ func RegisterResource(name string, typeFunc resourceFactory) {
 	resourceTypeRegistry[name] = typeFunc
 }
 
-func unmarshalResource(name, resType string, value map[string]interface{}) (Resource, error) {
+func (c *Config) unmarshalResource(
+	name, resType string,
+	value map[string]interface{},
+) (Resource, error) {
 	fromConfigFunc, ok := resourceTypeRegistry[resType]
 	if !ok {
-		return nil, fmt.Errorf("invalid resource type %q", resType)
+		executable, isPlugin := c.plugins[resType]
+		if !isPlugin {
+			return nil, fmt.Errorf("invalid resource type %q", resType)
+		}
+		fromConfigFunc = pluginFromConfig(resType, executable)
 	}
 	return fromConfigFunc(name, value)
 }
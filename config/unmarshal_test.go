@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/renstrom/dedent"
 	"gotest.tools/v3/assert"
 	is "gotest.tools/v3/assert/cmp"
@@ -73,7 +74,10 @@ func TestLoadFromBytes(t *testing.T) {
 	assert.DeepEqual(t, config, expected, cmpConfigOpt)
 }
 
-var cmpConfigOpt = cmp.AllowUnexported(PathGlobs{}, pull{}, ShlexSlice{})
+var cmpConfigOpt = cmp.Options{
+	cmp.AllowUnexported(PathGlobs{}, Includes{}, pull{}, ShlexSlice{}, Duration{}),
+	cmpopts.IgnoreUnexported(Config{}),
+}
 
 func TestLoadFromBytesWithReservedName(t *testing.T) {
 	conf := dedent.Dedent(`
@@ -100,3 +104,213 @@ func TestLoadFromBytesWithInvalidName(t *testing.T) {
 	_, err := LoadFromBytes([]byte(conf))
 	assert.Check(t, is.ErrorContains(err, `invalid character ":"`))
 }
+
+func TestLoadFromBytesWithWhen(t *testing.T) {
+	conf := dedent.Dedent(`
+		alias=release:
+		  tasks: []
+		  when: "{git.branch} == main"
+
+		alias=broken:
+		  tasks: []
+		  when: "{git.branch} =="
+	`)
+
+	config, err := LoadFromBytes([]byte(conf))
+	assert.NilError(t, err)
+	release := config.Resources["release"].(ConditionalResource)
+	assert.Check(t, is.Equal("{git.branch} == main", release.Condition()))
+	err = validate(config)
+	assert.Check(t, is.ErrorContains(err, "expected a value on both sides of =="))
+}
+
+func TestLoadFromBytesWithHooks(t *testing.T) {
+	conf := dedent.Dedent(`
+		compose=db:
+		  files: [docker-compose.yml]
+
+		job=test:
+		  use: builder
+		  hooks:
+		    before: [db:up]
+		    on-failure: [db:attach]
+		    after: [db:down]
+	`)
+
+	config, err := LoadFromBytes([]byte(conf))
+	assert.NilError(t, err)
+	expected := HooksConfig{
+		Before:    []string{"db:up"},
+		After:     []string{"db:down"},
+		OnFailure: []string{"db:attach"},
+	}
+	assert.Check(t, is.DeepEqual(expected, ResourceHooks(config.Resources["test"])))
+}
+
+func TestValidateHooksExist(t *testing.T) {
+	conf := dedent.Dedent(`
+		alias=test:
+		  tasks: []
+		  hooks:
+		    after: [cleanup]
+	`)
+
+	config, err := LoadFromBytes([]byte(conf))
+	assert.NilError(t, err)
+	err = validate(config)
+	assert.Check(t, is.ErrorContains(err, "missing dependencies: cleanup"))
+}
+
+func TestLoadFromBytesWithMatrix(t *testing.T) {
+	conf := dedent.Dedent(`
+		job=test-unit:
+		  use: builder-{matrix.go}
+		  command: "go test --arch={matrix.arch}"
+		  env:
+		    - "GOARCH={matrix.arch}"
+		  description: Run the unit tests
+		  matrix:
+		    go: ["1.21", "1.22"]
+		    arch: [amd64, arm64]
+	`)
+
+	config, err := LoadFromBytes([]byte(conf))
+	assert.NilError(t, err)
+
+	expected := []string{
+		"test-unit[arch=amd64,go=1.21]",
+		"test-unit[arch=amd64,go=1.22]",
+		"test-unit[arch=arm64,go=1.21]",
+		"test-unit[arch=arm64,go=1.22]",
+	}
+	alias, ok := config.Resources["test-unit"].(*AliasConfig)
+	assert.Assert(t, ok)
+	assert.Check(t, is.DeepEqual(expected, alias.Tasks))
+	assert.Check(t, is.Equal("Run the unit tests", alias.Describe()))
+
+	job, ok := config.Resources["test-unit[arch=arm64,go=1.22]"].(*JobConfig)
+	assert.Assert(t, ok)
+	assert.Check(t, is.Equal("builder-1.22", job.Use))
+	assert.Check(t, is.Equal("go test --arch=arm64", job.Command.String()))
+	assert.Check(t, is.DeepEqual([]string{"GOARCH=arm64"}, job.Env))
+	assert.Check(t, is.DeepEqual(MatrixValues{"go": "1.22", "arch": "arm64"}, job.Matrix))
+	assert.Check(t, is.Len(config.Resources, 5))
+}
+
+func TestLoadFromBytesWithInvalidMatrix(t *testing.T) {
+	conf := dedent.Dedent(`
+		job=test:
+		  use: builder
+		  matrix:
+		    go: []
+	`)
+
+	_, err := LoadFromBytes([]byte(conf))
+	assert.Check(t, is.ErrorContains(err, `matrix key "go" must be a non-empty list`))
+}
+
+func TestLoadFromBytesWithInvalidIncludeNamespace(t *testing.T) {
+	conf := dedent.Dedent(`
+		meta:
+		  include:
+		    - {file: shared.yaml, namespace: team.shared}
+	`)
+
+	_, err := LoadFromBytes([]byte(conf))
+	assert.Check(t, is.ErrorContains(err, `item 0 has an invalid namespace "team.shared"`))
+}
+
+func TestLoadFromBytesWithExtends(t *testing.T) {
+	conf := dedent.Dedent(`
+		job=base:
+		  use: builder
+		  mounts: [source]
+		  sources: src/
+		  interactive: true
+		  env: ["GOOS=linux"]
+		  labels:
+		    team: ci
+
+		job=test:
+		  extends: base
+		  command: go test ./...
+		  sources: [go.mod, pkg/]
+		  env: ["CGO_ENABLED=0"]
+		  mounts: [dist]
+		  labels:
+		    stage: test
+
+		image=base-image:
+		  image: builder
+		  args:
+		    VERSION: "1"
+
+		image=release-image:
+		  extends: base-image
+		  image: release
+		  args:
+		    DEBUG: "false"
+	`)
+
+	config, err := LoadFromBytes([]byte(conf))
+	assert.NilError(t, err)
+
+	job, ok := config.Resources["test"].(*JobConfig)
+	assert.Assert(t, ok)
+	assert.Check(t, is.Equal("builder", job.Use))
+	assert.Check(t, job.Interactive)
+	assert.Check(t, is.Equal("go test ./...", job.Command.String()))
+	assert.Check(t, is.DeepEqual([]string{"GOOS=linux", "CGO_ENABLED=0"}, job.Env))
+	assert.Check(t, is.DeepEqual([]string{"source", "dist"}, job.Mounts))
+	assert.Check(t, is.DeepEqual([]string{"go.mod", "pkg/"}, job.Sources.Globs()))
+	assert.Check(t, is.DeepEqual(map[string]string{"team": "ci", "stage": "test"}, job.Labels))
+	assert.Check(t, is.Equal("base", job.Extends))
+
+	image, ok := config.Resources["release-image"].(*ImageConfig)
+	assert.Assert(t, ok)
+	assert.Check(t, is.Equal("release", image.Image))
+	assert.Check(t, is.DeepEqual(map[string]string{"VERSION": "1", "DEBUG": "false"}, image.Args))
+}
+
+func TestLoadFromBytesWithInvalidExtends(t *testing.T) {
+	var testcases = []struct {
+		doc      string
+		conf     string
+		expected string
+	}{
+		{
+			doc: "different type",
+			conf: `
+				mount=source:
+				  bind: .
+				  path: /go
+				job=test:
+				  extends: source
+			`,
+			expected: `"source" is not a job resource in the same file, ` +
+				"resources from an include can not be extended",
+		},
+		{
+			doc: "unsupported type",
+			conf: `
+				alias=all:
+				  extends: other
+			`,
+			expected: "alias resources can not extend another resource",
+		},
+		{
+			doc: "cycle",
+			conf: `
+				job=one:
+				  extends: two
+				job=two:
+				  extends: one
+			`,
+			expected: "extends cycle: job=one -> job=two -> job=one",
+		},
+	}
+	for _, testcase := range testcases {
+		_, err := LoadFromBytes([]byte(dedent.Dedent(testcase.conf)))
+		assert.Check(t, is.ErrorContains(err, testcase.expected), testcase.doc)
+	}
+}
@@ -7,12 +7,14 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/dnephin/dobi/logging"
 	git "github.com/gogits/git-module"
 	"github.com/metakeule/fmtdate"
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 	fasttmpl "github.com/valyala/fasttemplate"
 )
 
@@ -27,8 +29,69 @@ type ExecEnv struct {
 	ExecID     string
 	Project    string
 	tmplCache  map[string]string
+	lock       sync.Mutex
 	workingDir string
 	startTime  time.Time
+	logger     *log.Logger
+	// planned are the environment variables which would be set by the tasks
+	// in a plan. They are used instead of the environment of the process.
+	planned map[string]plannedValue
+}
+
+// plannedValue is the value of an environment variable in a plan
+type plannedValue struct {
+	value string
+	// setBy is the task which sets the variable when the value is not known
+	// until the task runs
+	setBy string
+}
+
+// UnknownValueError is returned when a variable is resolved in a plan, and the
+// value of the variable is not known until a task runs
+type UnknownValueError struct {
+	Variable string
+	SetBy    string
+}
+
+func (e *UnknownValueError) Error() string {
+	return fmt.Sprintf("the value of %q is not known until %s runs", e.Variable, e.SetBy)
+}
+
+// SetPlannedVariable sets the value of an environment variable for resolving
+// variables, without changing the environment of the process
+func (e *ExecEnv) SetPlannedVariable(key, value string) {
+	e.setPlanned(key, plannedValue{value: value})
+}
+
+// SetUnknownVariable marks an environment variable as not known until the
+// task setBy runs. Resolving the variable returns an UnknownValueError.
+func (e *ExecEnv) SetUnknownVariable(key, setBy string) {
+	e.setPlanned(key, plannedValue{setBy: setBy})
+}
+
+func (e *ExecEnv) setPlanned(key string, value plannedValue) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.planned == nil {
+		e.planned = make(map[string]plannedValue)
+	}
+	e.planned[key] = value
+	// values resolved before the variable was set may use the variable
+	e.tmplCache = make(map[string]string)
+}
+
+// lookupEnv returns the value of an environment variable from the plan, or
+// from the environment of the process
+func (e *ExecEnv) lookupEnv(key string) (string, error) {
+	planned, ok := e.planned[key]
+	switch {
+	case !ok:
+		return os.Getenv(key), nil
+	case planned.setBy != "":
+		return "", &UnknownValueError{Variable: key, SetBy: planned.setBy}
+	default:
+		return planned.value, nil
+	}
 }
 
 // Unique returns a unique id for this execution
@@ -38,6 +101,8 @@ func (e *ExecEnv) Unique() string {
 
 // Resolve template variables to a string value and cache the value
 func (e *ExecEnv) Resolve(tmpl string) (string, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
 	if val, ok := e.tmplCache[tmpl]; ok {
 		return val, nil
 	}
@@ -88,9 +153,9 @@ func (e *ExecEnv) templateContext(out io.Writer, tag string) (int, error) {
 	prefix, suffix := splitPrefix(tag)
 	switch prefix {
 	case "env":
-		return write(os.Getenv(suffix), nil)
+		return write(e.lookupEnv(suffix))
 	case "git":
-		return valueFromGit(out, e.workingDir, suffix, defValue)
+		return valueFromGit(out, e.logger, e.workingDir, suffix, defValue)
 	case "time":
 		return write(fmtdate.Format(suffix, e.startTime), nil)
 	case "fs":
@@ -126,7 +191,12 @@ func valueFromFilesystem(name string, workingdir string) (string, error) {
 }
 
 // nolint: gocyclo
-func valueFromGit(out io.Writer, cwd string, tag, defValue string) (int, error) {
+func valueFromGit(
+	out io.Writer,
+	logger *log.Logger,
+	cwd string,
+	tag, defValue string,
+) (int, error) {
 	writeValue := func(value string) (int, error) {
 		return out.Write(bytes.NewBufferString(value).Bytes())
 	}
@@ -136,7 +206,7 @@ func valueFromGit(out io.Writer, cwd string, tag, defValue string) (int, error)
 			return 0, fmt.Errorf("failed resolving variable {git.%s}: %s", tag, err)
 		}
 
-		logging.Log.Warnf("Failed to get variable \"git.%s\", using default", tag)
+		logger.Warnf("Failed to get variable \"git.%s\", using default", tag)
 		return writeValue(defValue)
 	}
 
@@ -188,9 +258,15 @@ func splitPrefix(tag string) (string, string) {
 	}
 }
 
-// NewExecEnvFromConfig returns a new ExecEnv from a Config
-func NewExecEnvFromConfig(execID, project, workingDir string) (*ExecEnv, error) {
-	env := NewExecEnv(defaultExecID(), getProjectName(project, workingDir), workingDir)
+// NewExecEnvFromConfig returns a new ExecEnv from a Config. Warnings about the
+// config, and variables resolved with a default value, are logged to logger.
+func NewExecEnvFromConfig(
+	execID, project, workingDir string,
+	logger *log.Logger,
+) (*ExecEnv, error) {
+	project = getProjectName(logger, project, workingDir)
+	env := NewExecEnv(defaultExecID(), project, workingDir)
+	env.logger = logger
 	var err error
 	env.ExecID, err = getExecID(execID, env)
 	return env, err
@@ -204,15 +280,16 @@ func NewExecEnv(execID, project, workingDir string) *ExecEnv {
 		tmplCache:  make(map[string]string),
 		startTime:  time.Now(),
 		workingDir: workingDir,
+		logger:     logging.Log,
 	}
 }
 
-func getProjectName(project, workingDir string) string {
+func getProjectName(logger *log.Logger, project, workingDir string) string {
 	if project != "" {
 		return project
 	}
 	project = filepath.Base(workingDir)
-	logging.Log.Warnf("meta.project is not set. Using default %q.", project)
+	logger.Warnf("meta.project is not set. Using default %q.", project)
 	return project
 }
 
@@ -8,6 +8,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/dnephin/dobi/logging"
+	log "github.com/sirupsen/logrus"
 	"gotest.tools/v3/assert"
 	is "gotest.tools/v3/assert/cmp"
 	"gotest.tools/v3/fs"
@@ -16,19 +18,35 @@ import (
 func TestNewExecEnvFromConfigDefault(t *testing.T) {
 	tmpDir := fs.NewDir(t, "test-environment")
 	defer tmpDir.Remove()
-	execEnv, err := NewExecEnvFromConfig("", "", tmpDir.Path())
+	execEnv, err := NewExecEnvFromConfig("", "", tmpDir.Path(), logging.Log)
 	assert.NilError(t, err)
 	expected := fmt.Sprintf("%s-root", filepath.Base(tmpDir.Path()))
 	assert.Equal(t, expected, execEnv.Unique())
 }
 
+func TestNewExecEnvFromConfigLogsToLogger(t *testing.T) {
+	tmpDir := fs.NewDir(t, "test-environment")
+	defer tmpDir.Remove()
+	logs := new(bytes.Buffer)
+	logger := log.New()
+	logger.Out = logs
+
+	execEnv, err := NewExecEnvFromConfig("", "", tmpDir.Path(), logger)
+	assert.NilError(t, err)
+	assert.Check(t, is.Contains(logs.String(), "meta.project is not set"))
+
+	_, err = execEnv.Resolve("{git.branch:main}")
+	assert.NilError(t, err)
+	assert.Check(t, is.Contains(logs.String(), `Failed to get variable \"git.branch\"`))
+}
+
 func TestNewExecEnvFromConfigWithTemplate(t *testing.T) {
 	tmpDir := fs.NewDir(t, "test-environment")
 	defer tmpDir.Remove()
 	os.Setenv("EXEC_ID", "Use-This")
 	defer os.Unsetenv("EXEC_ID")
 
-	execEnv, err := NewExecEnvFromConfig("{env.EXEC_ID}", "", tmpDir.Path())
+	execEnv, err := NewExecEnvFromConfig("{env.EXEC_ID}", "", tmpDir.Path(), logging.Log)
 	assert.NilError(t, err)
 	assert.Equal(t, "Use-This", execEnv.ExecID)
 }
@@ -36,7 +54,7 @@ func TestNewExecEnvFromConfigWithTemplate(t *testing.T) {
 func TestNewExecEnvFromConfigWithInvalidTemplate(t *testing.T) {
 	tmpDir := fs.NewDir(t, "test-environment")
 	defer tmpDir.Remove()
-	_, err := NewExecEnvFromConfig("{env.bogus} ", "", tmpDir.Path())
+	_, err := NewExecEnvFromConfig("{env.bogus} ", "", tmpDir.Path(), logging.Log)
 	expected := `a value is required for variable "env.bogus"`
 	assert.Assert(t, is.ErrorContains(err, expected))
 }
@@ -194,7 +212,7 @@ func TestValueFromGit_DetachedHead(t *testing.T) {
 	for _, tc := range testcases {
 		t.Run(tc, func(t *testing.T) {
 			buf := new(bytes.Buffer)
-			_, err := valueFromGit(buf, tmpDir.Path(), tc, "")
+			_, err := valueFromGit(buf, logging.Log, tmpDir.Path(), tc, "")
 			expected := "failed resolving variable {git." + tc
 			assert.ErrorContains(t, err, expected, "value: %v", buf.String())
 		})
@@ -9,6 +9,8 @@ import (
 type FakeResource struct {
 	config.Annotations
 	config.Dependent
+	config.Conditional
+	config.Hookable
 }
 
 // Validate is a no-op
@@ -11,6 +11,6 @@ var (
 
 // ForTask returns a logger for a task which implemented LogRepresenter. The
 // logger has the task added as the `task` field.
-func ForTask(repr LogRepresenter) *log.Entry {
-	return Log.WithFields(log.Fields{"task": repr})
+func ForTask(logger *log.Logger, repr LogRepresenter) *log.Entry {
+	return logger.WithFields(log.Fields{"task": repr})
 }
@@ -1,12 +1,20 @@
 package main
 
 import (
+	"errors"
+	"os"
+
 	"github.com/dnephin/dobi/cmd"
 	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks"
 )
 
 func main() {
-	if err := cmd.NewRootCommand().Execute(); err != nil {
+	if err := cmd.Execute(os.Args[1:]); err != nil {
+		if errors.Is(err, tasks.ErrInterrupted) {
+			logging.Log.Error(err)
+			os.Exit(tasks.ExitCodeInterrupted)
+		}
 		logging.Log.Fatal(err)
 	}
 }
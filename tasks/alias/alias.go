@@ -25,8 +25,19 @@ func (t *Task) Repr() string {
 	return t.name.Format("alias")
 }
 
+// IsStale returns Fresh, because an alias is only modified when its
+// dependencies are modified
+func (t *Task) IsStale(_ *context.ExecuteContext) (types.Staleness, error) {
+	return types.Fresh("dependencies are fresh"), nil
+}
+
 // Run does nothing. Dependencies were already run.
 func (t *Task) Run(ctx *context.ExecuteContext, depsModified bool) (bool, error) {
-	logging.ForTask(t).Info("Done")
-	return depsModified, nil
+	staleness, err := types.CheckStaleness(ctx, t.name, depsModified,
+		func() (types.Staleness, error) { return t.IsStale(ctx) })
+	if err != nil {
+		return false, err
+	}
+	logging.ForTask(ctx.Logger, t).Info("Done")
+	return staleness.Stale, nil
 }
@@ -0,0 +1,56 @@
+package tasks
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/task"
+)
+
+// argsForJobs returns the args for each job run by the tasks of the options, or
+// by the default task when the options have no tasks. Jobs are run either
+// directly or through an alias. The args are not passed to the dependencies of
+// a task.
+func argsForJobs(options RunOptions) (map[string][]string, error) {
+	args := options.Args
+	if len(args) == 0 {
+		return nil, nil
+	}
+	names := getNames(options)
+	jobs := make(map[string][]string)
+	addJobArgs(options.Config, names, args, jobs, make(map[string]bool))
+	if len(jobs) == 0 {
+		return nil, fmt.Errorf(
+			"arguments after -- can only be passed to a job, but %s does not run a job",
+			strings.Join(names, ", "))
+	}
+	return jobs, nil
+}
+
+func addJobArgs(
+	conf *config.Config,
+	names []string,
+	args []string,
+	jobs map[string][]string,
+	seen map[string]bool,
+) {
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		taskname := task.ParseName(name)
+		switch resource := conf.Resources[taskname.Resource()].(type) {
+		case *config.JobConfig:
+			switch taskname.Action() {
+			case "remove", "rm":
+			default:
+				jobs[taskname.Resource()] = args
+			}
+		case *config.AliasConfig:
+			addJobArgs(conf, resource.Tasks, args, jobs, seen)
+		}
+	}
+}
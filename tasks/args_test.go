@@ -0,0 +1,58 @@
+package tasks
+
+import (
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestArgsForJobs(t *testing.T) {
+	conf := config.NewConfig()
+	conf.Resources["unit"] = &config.JobConfig{}
+	conf.Resources["lint"] = &config.JobConfig{}
+	conf.Resources["clean"] = &config.JobConfig{}
+	conf.Resources["builder"] = config.NewImageConfig()
+	conf.Resources["test"] = &config.AliasConfig{Tasks: []string{"unit", "builder"}}
+
+	args := []string{"-run", "TestFoo"}
+	jobs, err := argsForJobs(RunOptions{
+		Config: conf,
+		Tasks:  []string{"test", "lint:run", "clean:rm"},
+		Args:   args,
+	})
+	assert.NilError(t, err)
+	expected := map[string][]string{"unit": args, "lint": args}
+	assert.Check(t, is.DeepEqual(expected, jobs))
+}
+
+func TestArgsForJobsWithoutArgs(t *testing.T) {
+	jobs, err := argsForJobs(RunOptions{Config: config.NewConfig(), Tasks: []string{"test"}})
+	assert.NilError(t, err)
+	assert.Check(t, is.Nil(jobs))
+}
+
+func TestArgsForJobsWithoutAJob(t *testing.T) {
+	conf := config.NewConfig()
+	conf.Resources["builder"] = config.NewImageConfig()
+
+	_, err := argsForJobs(RunOptions{
+		Config: conf,
+		Tasks:  []string{"builder"},
+		Args:   []string{"-v"},
+	})
+	assert.Check(t, is.Error(err,
+		"arguments after -- can only be passed to a job, but builder does not run a job"))
+}
+
+func TestArgsForJobsWithDefaultTask(t *testing.T) {
+	conf := config.NewConfig()
+	conf.Resources["unit"] = &config.JobConfig{}
+	conf.Meta.Default = "unit"
+
+	args := []string{"-run", "TestFoo"}
+	jobs, err := argsForJobs(RunOptions{Config: conf, Args: args})
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(map[string][]string{"unit": args}, jobs))
+}
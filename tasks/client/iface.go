@@ -1,6 +1,8 @@
 package client
 
 import (
+	"context"
+
 	docker "github.com/fsouza/go-dockerclient"
 )
 
@@ -12,15 +14,16 @@ type DockerClient interface {
 	InspectImage(string) (*docker.Image, error)
 	PushImage(docker.PushImageOptions, docker.AuthConfiguration) error
 	PullImage(docker.PullImageOptions, docker.AuthConfiguration) error
-	RemoveImage(string) error
+	RemoveImageExtended(string, docker.RemoveImageOptions) error
 	TagImage(string, docker.TagImageOptions) error
 
 	AttachToContainerNonBlocking(docker.AttachToContainerOptions) (docker.CloseWaiter, error)
 	CreateContainer(docker.CreateContainerOptions) (*docker.Container, error)
 	KillContainer(docker.KillContainerOptions) error
 	RemoveContainer(docker.RemoveContainerOptions) error
-	StartContainer(string, *docker.HostConfig) error
-	WaitContainer(string) (int, error)
+	StartContainerWithContext(string, *docker.HostConfig, context.Context) error
+	StopContainer(id string, timeout uint) error
+	WaitContainerWithContext(string, context.Context) (int, error)
 	DownloadFromContainer(id string, opts docker.DownloadFromContainerOptions) error
 
 	CreateVolume(opts docker.CreateVolumeOptions) (*docker.Volume, error)
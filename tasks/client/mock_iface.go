@@ -4,6 +4,7 @@
 package client
 
 import (
+	context "context"
 	go_dockerclient "github.com/fsouza/go-dockerclient"
 	gomock "github.com/golang/mock/gomock"
 	reflect "reflect"
@@ -81,16 +82,16 @@ func (_mr *MockDockerClientMockRecorder) PullImage(arg0, arg1 interface{}) *gomo
 	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "PullImage", reflect.TypeOf((*MockDockerClient)(nil).PullImage), arg0, arg1)
 }
 
-// RemoveImage mocks base method
-func (_m *MockDockerClient) RemoveImage(_param0 string) error {
-	ret := _m.ctrl.Call(_m, "RemoveImage", _param0)
+// RemoveImageExtended mocks base method
+func (_m *MockDockerClient) RemoveImageExtended(_param0 string, _param1 go_dockerclient.RemoveImageOptions) error {
+	ret := _m.ctrl.Call(_m, "RemoveImageExtended", _param0, _param1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// RemoveImage indicates an expected call of RemoveImage
-func (_mr *MockDockerClientMockRecorder) RemoveImage(arg0 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "RemoveImage", reflect.TypeOf((*MockDockerClient)(nil).RemoveImage), arg0)
+// RemoveImageExtended indicates an expected call of RemoveImageExtended
+func (_mr *MockDockerClientMockRecorder) RemoveImageExtended(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "RemoveImageExtended", reflect.TypeOf((*MockDockerClient)(nil).RemoveImageExtended), arg0, arg1)
 }
 
 // TagImage mocks base method
@@ -155,29 +156,41 @@ func (_mr *MockDockerClientMockRecorder) RemoveContainer(arg0 interface{}) *gomo
 	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "RemoveContainer", reflect.TypeOf((*MockDockerClient)(nil).RemoveContainer), arg0)
 }
 
-// StartContainer mocks base method
-func (_m *MockDockerClient) StartContainer(_param0 string, _param1 *go_dockerclient.HostConfig) error {
-	ret := _m.ctrl.Call(_m, "StartContainer", _param0, _param1)
+// StartContainerWithContext mocks base method
+func (_m *MockDockerClient) StartContainerWithContext(_param0 string, _param1 *go_dockerclient.HostConfig, _param2 context.Context) error {
+	ret := _m.ctrl.Call(_m, "StartContainerWithContext", _param0, _param1, _param2)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// StartContainer indicates an expected call of StartContainer
-func (_mr *MockDockerClientMockRecorder) StartContainer(arg0, arg1 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "StartContainer", reflect.TypeOf((*MockDockerClient)(nil).StartContainer), arg0, arg1)
+// StartContainerWithContext indicates an expected call of StartContainerWithContext
+func (_mr *MockDockerClientMockRecorder) StartContainerWithContext(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "StartContainerWithContext", reflect.TypeOf((*MockDockerClient)(nil).StartContainerWithContext), arg0, arg1, arg2)
 }
 
-// WaitContainer mocks base method
-func (_m *MockDockerClient) WaitContainer(_param0 string) (int, error) {
-	ret := _m.ctrl.Call(_m, "WaitContainer", _param0)
+// StopContainer mocks base method
+func (_m *MockDockerClient) StopContainer(id string, timeout uint) error {
+	ret := _m.ctrl.Call(_m, "StopContainer", id, timeout)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StopContainer indicates an expected call of StopContainer
+func (_mr *MockDockerClientMockRecorder) StopContainer(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "StopContainer", reflect.TypeOf((*MockDockerClient)(nil).StopContainer), arg0, arg1)
+}
+
+// WaitContainerWithContext mocks base method
+func (_m *MockDockerClient) WaitContainerWithContext(_param0 string, _param1 context.Context) (int, error) {
+	ret := _m.ctrl.Call(_m, "WaitContainerWithContext", _param0, _param1)
 	ret0, _ := ret[0].(int)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// WaitContainer indicates an expected call of WaitContainer
-func (_mr *MockDockerClientMockRecorder) WaitContainer(arg0 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "WaitContainer", reflect.TypeOf((*MockDockerClient)(nil).WaitContainer), arg0)
+// WaitContainerWithContext indicates an expected call of WaitContainerWithContext
+func (_mr *MockDockerClientMockRecorder) WaitContainerWithContext(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "WaitContainerWithContext", reflect.TypeOf((*MockDockerClient)(nil).WaitContainerWithContext), arg0, arg1)
 }
 
 // DownloadFromContainer mocks base method
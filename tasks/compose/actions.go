@@ -70,21 +70,21 @@ func NewTask(run actionFunc, stop actionFunc) func(task.Name, config.Resource) t
 }
 
 // RunUp starts the Compose project
-func RunUp(_ *context.ExecuteContext, t *Task) error {
-	t.logger().Info("project up")
-	return t.execCompose("up", "-d")
+func RunUp(ctx *context.ExecuteContext, t *Task) error {
+	t.logger(ctx).Info("project up")
+	return t.execCompose(ctx, "up", "-d")
 }
 
 // StopUp stops the project
-func StopUp(_ *context.ExecuteContext, t *Task) error {
-	t.logger().Info("project stop")
-	return t.execCompose("stop", "-t", t.config.StopGraceString())
+func StopUp(ctx *context.ExecuteContext, t *Task) error {
+	t.logger(ctx).Info("project stop")
+	return t.execCompose(ctx, "stop", "-t", t.config.StopGraceString())
 }
 
 // RunDown removes all the project resources
-func RunDown(_ *context.ExecuteContext, t *Task) error {
-	t.logger().Info("project down")
-	return t.execCompose("down")
+func RunDown(ctx *context.ExecuteContext, t *Task) error {
+	t.logger(ctx).Info("project down")
+	return t.execCompose(ctx, "down")
 }
 
 func deps(conf *config.ComposeConfig) func() []string {
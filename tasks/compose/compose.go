@@ -1,10 +1,12 @@
 package compose
 
 import (
+	gocontext "context"
 	"fmt"
 	"os"
 	"os/exec"
 	"strings"
+	"syscall"
 
 	"github.com/dnephin/dobi/config"
 	"github.com/dnephin/dobi/logging"
@@ -26,8 +28,8 @@ func (t *Task) Name() task.Name {
 	return t.name
 }
 
-func (t *Task) logger() *log.Entry {
-	return logging.ForTask(t)
+func (t *Task) logger(ctx *context.ExecuteContext) *log.Entry {
+	return logging.ForTask(ctx.Logger, t)
 }
 
 // Repr formats the task for logging
@@ -38,12 +40,14 @@ func (t *Task) Repr() string {
 
 // Run runs the action
 func (t *Task) Run(ctx *context.ExecuteContext, _ bool) (bool, error) {
-	return false, t.run(ctx, t)
+	ctx, cancel := ctx.WithTimeout(t.config.Timeout.Value())
+	defer cancel()
+	return false, ctx.TimeoutError(t.run(ctx, t))
 }
 
 // Stop the task
 func (t *Task) Stop(ctx *context.ExecuteContext) error {
-	t.logger().Debug("Stop")
+	t.logger(ctx).Debug("Stop")
 	return t.stop(ctx, t)
 }
 
@@ -60,19 +64,70 @@ func buildCommandArgs(conf *config.ComposeConfig) []string {
 	return append(args, "-p", conf.Project)
 }
 
-func (t *Task) execCompose(args ...string) error {
-	if err := t.buildCommand(args...).Run(); err != nil {
+func (t *Task) execCompose(ctx *context.ExecuteContext, args ...string) error {
+	if err := t.runCommand(ctx, t.buildCommand(ctx, args...)); err != nil {
 		return err
 	}
-	t.logger().Info("Done")
+	t.logger(ctx).Info("Done")
 	return nil
 }
 
-func (t *Task) buildCommand(args ...string) *exec.Cmd {
+func (t *Task) buildCommand(ctx *context.ExecuteContext, args ...string) *exec.Cmd {
 	args = append(buildCommandArgs(t.config), args...)
 	cmd := exec.Command("docker-compose", args...)
-	t.logger().Debugf("Args: %s", args)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	t.logger(ctx).Debugf("Args: %s", args)
+	cmd.Stdout = ctx.Stdout
+	cmd.Stderr = ctx.Stderr
 	return cmd
 }
+
+// runCommand starts the command and waits for it to exit. The command is
+// stopped when the Context is cancelled.
+func (t *Task) runCommand(ctx *context.ExecuteContext, cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	stopWaiting := t.stopOnCancel(ctx, cmd.Process)
+	defer close(stopWaiting)
+	return cmd.Wait()
+}
+
+// stopOnCancel sends SIGTERM to docker-compose when the Context is cancelled,
+// so that it can stop the containers of the project gracefully. The process
+// is killed immediately when the task times out, or when the Kill Context is
+// cancelled. Closing the returned channel stops waiting for the Contexts.
+func (t *Task) stopOnCancel(ctx *context.ExecuteContext, proc *os.Process) chan<- struct{} {
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Context.Done():
+		case <-stop:
+			return
+		}
+		if ctx.Context.Err() == gocontext.DeadlineExceeded {
+			t.killProcess(ctx, proc, ctx.Context.Err())
+			return
+		}
+
+		t.logger(ctx).Warnf("Stopping docker-compose: %s", ctx.Context.Err())
+		t.signalProcess(ctx, proc, syscall.SIGTERM)
+		select {
+		case <-ctx.Kill.Done():
+			t.killProcess(ctx, proc, ctx.Kill.Err())
+		case <-stop:
+		}
+	}()
+	return stop
+}
+
+func (t *Task) killProcess(ctx *context.ExecuteContext, proc *os.Process, reason error) {
+	t.logger(ctx).Warnf("Killing docker-compose: %s", reason)
+	t.signalProcess(ctx, proc, os.Kill)
+}
+
+func (t *Task) signalProcess(ctx *context.ExecuteContext, proc *os.Process, sig os.Signal) {
+	if err := proc.Signal(sig); err != nil {
+		t.logger(ctx).WithFields(log.Fields{"signal": sig, "pid": proc.Pid}).Warnf(
+			"failed to signal process: %s", err)
+	}
+}
@@ -0,0 +1,93 @@
+// +build !windows
+
+package compose
+
+import (
+	"bufio"
+	gocontext "context"
+	"io"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	"gotest.tools/v3/assert"
+)
+
+// runTrapCommand runs a shell which runs trap when it receives SIGTERM, and
+// calls interrupt once the shell is ready to handle the signal.
+func runTrapCommand(
+	t *testing.T,
+	ctx *context.ExecuteContext,
+	trap string,
+	interrupt func(),
+) error {
+	task := &Task{name: task.NewDefaultName("test", "up"), config: &config.ComposeConfig{}}
+	reader, writer := io.Pipe()
+	defer reader.Close() // nolint: errcheck
+	cmd := exec.Command("sh", "-c",
+		"trap '"+trap+"' TERM; echo ready; sleep 10 >/dev/null & wait")
+	cmd.Stdout = writer
+
+	done := make(chan error, 1)
+	go func() {
+		done <- task.runCommand(ctx, cmd)
+		writer.Close() // nolint: errcheck
+	}()
+
+	line, err := bufio.NewReader(reader).ReadString('\n')
+	assert.NilError(t, err)
+	assert.Equal(t, line, "ready\n")
+	interrupt()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the command to exit")
+	}
+	return nil
+}
+
+func exitStatus(t *testing.T, err error) string {
+	t.Helper()
+	exitErr, ok := err.(*exec.ExitError)
+	assert.Assert(t, ok, "expected *exec.ExitError, got %T: %s", err, err)
+	return exitErr.ProcessState.String()
+}
+
+func TestRunCommandSendsSIGTERMWhenCancelled(t *testing.T) {
+	gctx, cancel := gocontext.WithCancel(gocontext.Background())
+	defer cancel()
+	ctx := &context.ExecuteContext{
+		Context: gctx,
+		Kill:    gocontext.Background(),
+		Logger:  logging.Log,
+	}
+
+	err := runTrapCommand(t, ctx, "exit 3", cancel)
+	assert.Equal(t, exitStatus(t, err), "exit status 3")
+}
+
+func TestRunCommandKillsProcessWhenKilled(t *testing.T) {
+	gctx, cancel := gocontext.WithCancel(gocontext.Background())
+	defer cancel()
+	kill, cancelKill := gocontext.WithCancel(gocontext.Background())
+	defer cancelKill()
+	ctx := &context.ExecuteContext{
+		Context: gctx,
+		Kill:    kill,
+		Logger:  logging.Log,
+	}
+
+	// the shell ignores SIGTERM, so only SIGKILL stops it
+	interrupt := func() {
+		cancelKill()
+		cancel()
+	}
+	err := runTrapCommand(t, ctx, "", interrupt)
+	assert.Equal(t, exitStatus(t, err), "signal: killed")
+}
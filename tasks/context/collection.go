@@ -1,6 +1,8 @@
 package context
 
 import (
+	"sync"
+
 	"github.com/dnephin/dobi/config"
 )
 
@@ -8,12 +10,15 @@ import (
 // TODO: this type can be removed if config.Config is changed to store resources
 // grouped by type, instead of as a single map
 type ResourceCollection struct {
+	lock   sync.RWMutex
 	mounts map[string]*config.MountConfig
 	images map[string]*config.ImageConfig
 }
 
 // Add a resource to the collection
 func (c *ResourceCollection) Add(name string, resource config.Resource) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	switch resource := resource.(type) {
 	case *config.MountConfig:
 		c.mounts[name] = resource
@@ -24,11 +29,15 @@ func (c *ResourceCollection) Add(name string, resource config.Resource) {
 
 // Mount returns a config.MountConfig by name
 func (c *ResourceCollection) Mount(name string) *config.MountConfig {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
 	return c.mounts[name]
 }
 
 // Image returns an config.ImageConfig by name
 func (c *ResourceCollection) Image(name string) *config.ImageConfig {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
 	return c.images[name]
 }
 
@@ -37,8 +46,7 @@ type eachMountFunc func(name string, vol *config.MountConfig)
 // EachMount iterates all the mounts in names and calls f for each
 func (c *ResourceCollection) EachMount(names []string, f eachMountFunc) {
 	for _, name := range names {
-		mount := c.mounts[name]
-		f(name, mount)
+		f(name, c.Mount(name))
 	}
 }
 
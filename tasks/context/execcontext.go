@@ -1,31 +1,65 @@
 package context
 
 import (
+	gocontext "context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
 	"github.com/dnephin/dobi/config"
 	"github.com/dnephin/dobi/execenv"
 	"github.com/dnephin/dobi/logging"
 	"github.com/dnephin/dobi/tasks/client"
+	"github.com/dnephin/dobi/tasks/events"
 	"github.com/dnephin/dobi/tasks/task"
 	docker "github.com/fsouza/go-dockerclient"
+	log "github.com/sirupsen/logrus"
 )
 
 // ExecuteContext contains all the context for task execution
 type ExecuteContext struct {
-	modified    map[string]bool
+	modified    *modifiedTasks
 	Resources   *ResourceCollection
 	Client      client.DockerClient
-	authConfigs *docker.AuthConfigurations
+	authConfigs *authConfigs
 	WorkingDir  string
 	ConfigFile  string
 	Env         *execenv.ExecEnv
 	Settings    Settings
+	Stdout      io.Writer
+	Stderr      io.Writer
+	// Context is cancelled when the task should stop running
+	Context gocontext.Context
+	// Kill is cancelled when the task should stop immediately, without waiting
+	// for it to stop gracefully after Context was cancelled
+	Kill    gocontext.Context
+	timeout time.Duration
+	// Events receives an event at each point in the lifecycle of a task
+	Events *events.Emitter
+	// Logger receives the log messages from the tasks
+	Logger *log.Logger
+}
+
+// modifiedTasks is the set of tasks modified during this execution. It is safe
+// for concurrent use, so tasks can be run in parallel.
+type modifiedTasks struct {
+	lock  sync.RWMutex
+	names map[string]bool
+}
+
+func newModifiedTasks() *modifiedTasks {
+	return &modifiedTasks{names: make(map[string]bool)}
 }
 
 // IsModified returns true if any of the tasks named in names has been modified
 // during this execution
 func (ctx *ExecuteContext) IsModified(names ...task.Name) bool {
+	ctx.modified.lock.RLock()
+	defer ctx.modified.lock.RUnlock()
 	for _, name := range names {
-		if modified := ctx.modified[name.MapKey()]; modified {
+		if modified := ctx.modified.names[name.MapKey()]; modified {
 			return true
 		}
 	}
@@ -36,18 +70,104 @@ func (ctx *ExecuteContext) IsModified(names ...task.Name) bool {
 func (ctx *ExecuteContext) SetModified(name task.Name) {
 	// Add both the key and the string name so that it matches against
 	// dependencies specified with or without an action
-	ctx.modified[name.MapKey()] = true
-	ctx.modified[name.Name()] = true
+	ctx.modified.lock.Lock()
+	defer ctx.modified.lock.Unlock()
+	ctx.modified.names[name.MapKey()] = true
+	ctx.modified.names[name.Name()] = true
+}
+
+// WithOutput returns a copy of the ExecuteContext which writes task output to
+// stdout and stderr. The copy shares all other state with the original.
+func (ctx *ExecuteContext) WithOutput(stdout, stderr io.Writer) *ExecuteContext {
+	taskCtx := *ctx
+	taskCtx.Stdout = stdout
+	taskCtx.Stderr = stderr
+	return &taskCtx
+}
+
+// WithContext returns a copy of the ExecuteContext which uses gctx for
+// cancellation. The copy shares all other state with the original.
+func (ctx *ExecuteContext) WithContext(gctx gocontext.Context) *ExecuteContext {
+	taskCtx := *ctx
+	taskCtx.Context = gctx
+	return &taskCtx
+}
+
+// Emit sends an event to the event listeners
+func (ctx *ExecuteContext) Emit(event events.Event) {
+	ctx.Events.Emit(event)
+}
+
+// Span emits a SpanStarted event for a step of a task, and returns a function
+// which emits the SpanFinished event when the step is complete
+func (ctx *ExecuteContext) Span(name task.Name, span string) func() {
+	start := time.Now()
+	ctx.Emit(events.NewSpanEvent(events.SpanStarted, name, span))
+	return func() {
+		event := events.NewSpanEvent(events.SpanFinished, name, span)
+		event.Duration = time.Since(start)
+		ctx.Emit(event)
+	}
+}
+
+// WithTimeout returns a copy of the ExecuteContext with a Context that is
+// cancelled after timeout. If timeout is 0 Settings.Timeout is used instead,
+// and if both are 0 the Context is never cancelled by a timeout.
+func (ctx *ExecuteContext) WithTimeout(
+	timeout time.Duration,
+) (*ExecuteContext, gocontext.CancelFunc) {
+	if timeout == 0 {
+		timeout = ctx.Settings.Timeout
+	}
+	taskCtx := *ctx
+	if timeout == 0 {
+		return &taskCtx, func() {}
+	}
+	var cancel gocontext.CancelFunc
+	taskCtx.Context, cancel = gocontext.WithTimeout(ctx.Context, timeout)
+	taskCtx.timeout = timeout
+	return &taskCtx, cancel
+}
+
+// TimeoutError returns a timeout error in place of err if the Context timed
+// out, otherwise it returns err.
+func (ctx *ExecuteContext) TimeoutError(err error) error {
+	if err != nil && ctx.Context.Err() == gocontext.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s", ctx.timeout)
+	}
+	return err
+}
+
+// authConfigs are loaded from the docker config file the first time they are
+// used. They are shared by the copies of an ExecuteContext.
+type authConfigs struct {
+	once    sync.Once
+	configs *docker.AuthConfigurations
+}
+
+func (a *authConfigs) get(logger *log.Logger) *docker.AuthConfigurations {
+	if a == nil {
+		return nil
+	}
+	a.once.Do(func() {
+		configs, err := docker.NewAuthConfigurationsFromDockerCfg()
+		if err != nil {
+			logger.Warnf("Failed to load auth config: %s", err)
+		}
+		a.configs = configs
+	})
+	return a.configs
 }
 
 // GetAuthConfig returns the auth configuration for the repo
 func (ctx *ExecuteContext) GetAuthConfig(repo string) docker.AuthConfiguration {
-	if ctx.authConfigs == nil {
+	configs := ctx.authConfigs.get(ctx.Logger)
+	if configs == nil {
 		return docker.AuthConfiguration{}
 	}
-	auth, ok := ctx.authConfigs.Configs[repo]
+	auth, ok := configs.Configs[repo]
 	if !ok {
-		logging.Log.Warnf("Missing auth config for %q", repo)
+		ctx.Logger.Warnf("Missing auth config for %q", repo)
 	}
 	return auth
 }
@@ -56,10 +176,11 @@ func (ctx *ExecuteContext) GetAuthConfig(repo string) docker.AuthConfiguration {
 // is used by build, because the repo isn't known until after the Dockerfile is
 // parsed.
 func (ctx *ExecuteContext) GetAuthConfigs() docker.AuthConfigurations {
-	if ctx.authConfigs == nil {
+	configs := ctx.authConfigs.get(ctx.Logger)
+	if configs == nil {
 		return docker.AuthConfigurations{}
 	}
-	return *ctx.authConfigs
+	return *configs
 }
 
 // NewExecuteContext craetes a new empty ExecuteContext
@@ -69,19 +190,19 @@ func NewExecuteContext(
 	execEnv *execenv.ExecEnv,
 	settings Settings,
 ) *ExecuteContext {
-	authConfigs, err := docker.NewAuthConfigurationsFromDockerCfg()
-	if err != nil {
-		logging.Log.Warnf("Failed to load auth config: %s", err)
-	}
-
 	return &ExecuteContext{
-		modified:    make(map[string]bool),
+		modified:    newModifiedTasks(),
 		Resources:   newResourceCollection(),
 		WorkingDir:  config.WorkingDir,
 		Client:      client,
-		authConfigs: authConfigs,
+		authConfigs: &authConfigs{},
 		ConfigFile:  config.FilePath,
 		Env:         execEnv,
 		Settings:    settings,
+		Stdout:      os.Stdout,
+		Stderr:      os.Stderr,
+		Context:     gocontext.Background(),
+		Kill:        gocontext.Background(),
+		Logger:      logging.Log,
 	}
 }
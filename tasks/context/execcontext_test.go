@@ -1,8 +1,12 @@
 package context
 
 import (
+	gocontext "context"
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/dnephin/dobi/tasks/events"
 	"github.com/dnephin/dobi/tasks/task"
 	docker "github.com/fsouza/go-dockerclient"
 	"gotest.tools/v3/assert"
@@ -16,7 +20,7 @@ func TestExecuteContext_GetAuthConfig_NoAuthConfig(t *testing.T) {
 }
 
 func TestExecuteContext_IsModified(t *testing.T) {
-	context := &ExecuteContext{modified: make(map[string]bool)}
+	context := &ExecuteContext{modified: newModifiedTasks()}
 	context.SetModified(task.ParseName("task1"))
 	context.SetModified(task.NewDefaultName("task2", "pull"))
 	context.SetModified(task.ParseName("task3:rm"))
@@ -62,3 +66,46 @@ func TestExecuteContext_IsModified(t *testing.T) {
 		})
 	}
 }
+
+func TestExecuteContext_WithTimeout(t *testing.T) {
+	ctx := &ExecuteContext{
+		Context:  gocontext.Background(),
+		Settings: Settings{Timeout: time.Hour},
+	}
+
+	taskCtx, cancel := ctx.WithTimeout(time.Millisecond)
+	defer cancel()
+	<-taskCtx.Context.Done()
+	err := taskCtx.TimeoutError(fmt.Errorf("exited with non-zero status code 137"))
+	assert.Check(t, is.Error(err, "timed out after 1ms"))
+	assert.Check(t, is.Nil(ctx.Context.Err()))
+
+	taskCtx, cancel = ctx.WithTimeout(0)
+	defer cancel()
+	deadline, _ := taskCtx.Context.Deadline()
+	assert.Check(t, time.Until(deadline) > 59*time.Minute)
+	assert.Check(t, is.Error(taskCtx.TimeoutError(fmt.Errorf("broken")), "broken"))
+}
+
+type eventRecorder struct {
+	events []events.Event
+}
+
+func (r *eventRecorder) Handle(event events.Event) {
+	r.events = append(r.events, event)
+}
+
+func TestExecuteContext_Span(t *testing.T) {
+	recorder := &eventRecorder{}
+	ctx := &ExecuteContext{Events: events.NewEmitter(recorder)}
+	name := task.NewDefaultName("builder", "build")
+
+	ctx.Span(name, "build image")()
+	assert.Assert(t, is.Len(recorder.events, 2))
+	started, finished := recorder.events[0], recorder.events[1]
+	assert.Check(t, is.Equal(started.Type, events.SpanStarted))
+	assert.Check(t, is.Equal(started.Span, "build image"))
+	assert.Check(t, is.Equal(finished.Type, events.SpanFinished))
+	assert.Check(t, is.Equal(finished.Span, "build image"))
+	assert.Check(t, finished.Duration >= 0)
+}
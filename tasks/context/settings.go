@@ -1,10 +1,22 @@
 package context
 
+import "time"
+
 // Settings are flags that can be set by a user to change the behaviour of some
 // tasks
 type Settings struct {
 	Quiet     bool
 	BindMount bool
+	// Parallel is the maximum number of tasks to run at the same time
+	Parallel int
+	// KeepGoing continues to run tasks after a task fails
+	KeepGoing bool
+	// Timeout is the maximum time a task may run when the resource does not
+	// set a timeout
+	Timeout time.Duration
+	// Args are the arguments from the command line for the command of each
+	// job, by resource name
+	Args map[string][]string
 }
 
 // NewSettings returns a new Settings
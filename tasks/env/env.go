@@ -49,26 +49,71 @@ func (t *Task) Repr() string {
 }
 
 // Run sets environment variables
-func (t *Task) Run(_ *context.ExecuteContext, _ bool) (bool, error) {
-	var modified int
-	for _, filename := range t.config.Files {
-		vars, err := opts.ParseEnvFile(filename)
+func (t *Task) Run(ctx *context.ExecuteContext, _ bool) (bool, error) {
+	_, err := types.CheckStaleness(ctx, t.name, false,
+		func() (types.Staleness, error) { return t.IsStale(ctx) })
+	if err != nil {
+		return false, err
+	}
+	vars, err := t.variables()
+	if err != nil {
+		return false, err
+	}
+	modified, err := setVariables(vars)
+	if err != nil {
+		return false, err
+	}
+	logging.ForTask(ctx.Logger, t).Info("Done")
+	return modified > 0, nil
+}
+
+// IsStale returns Stale when any of the variables has a different value in the
+// environment
+func (t *Task) IsStale(_ *context.ExecuteContext) (types.Staleness, error) {
+	vars, err := t.variables()
+	if err != nil {
+		return types.Staleness{}, err
+	}
+	for _, variable := range vars {
+		key, value, err := splitVar(variable)
 		if err != nil {
-			return false, err
+			return types.Staleness{}, err
 		}
-		count, err := setVariables(vars)
-		if err != nil {
-			return false, err
+		if current, ok := os.LookupEnv(key); !ok || current != value {
+			return types.Stale(fmt.Sprintf("%s will be changed", key)), nil
 		}
-		modified += count
 	}
-	count, err := setVariables(t.config.Variables)
+	return types.Fresh("variables are already set"), nil
+}
+
+// Plan records the variables in the ExecEnv without setting them
+func (t *Task) Plan(ctx *context.ExecuteContext) error {
+	vars, err := t.variables()
 	if err != nil {
-		return false, err
+		return err
 	}
-	modified += count
-	logging.ForTask(t).Info("Done")
-	return modified > 0, nil
+	for _, variable := range vars {
+		key, value, err := splitVar(variable)
+		if err != nil {
+			return err
+		}
+		ctx.Env.SetPlannedVariable(key, value)
+	}
+	return nil
+}
+
+// variables returns the variables from the files, followed by the variables
+// from the config
+func (t *Task) variables() ([]string, error) {
+	all := []string{}
+	for _, filename := range t.config.Files {
+		vars, err := opts.ParseEnvFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, vars...)
+	}
+	return append(all, t.config.Variables...), nil
 }
 
 func setVariables(vars []string) (int, error) {
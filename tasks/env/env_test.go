@@ -6,6 +6,8 @@ import (
 	"os"
 
 	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks/context"
 	"github.com/dnephin/dobi/tasks/task"
 	"gotest.tools/v3/assert"
 	"gotest.tools/v3/env"
@@ -43,7 +45,7 @@ func TestTask_Run(t *testing.T) {
 				Variables: toSlice(tc.vars),
 			})
 
-			modified, err := envTask.Run(nil, false)
+			modified, err := envTask.Run(&context.ExecuteContext{Logger: logging.Log}, false)
 			assert.NilError(t, err)
 			assert.Equal(t, modified, tc.expected)
 
@@ -0,0 +1,159 @@
+// Package events provides the events emitted at each point in the lifecycle of
+// a task, and the listeners that receive them.
+package events
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/dnephin/dobi/tasks/task"
+)
+
+// Type is the type of an Event
+type Type string
+
+// Types of events, in the order they are emitted for a task
+const (
+	// Collected is emitted for each task before any task is run
+	Collected Type = "collected"
+	// Started is emitted before the task is run
+	Started Type = "started"
+	// Stale is emitted when a task decides it needs to run
+	Stale Type = "stale"
+	// Fresh is emitted when a task decides it is up to date
+	Fresh Type = "fresh"
+	// Finished is emitted after the task is run, successfully or not
+	Finished Type = "finished"
+	// Skipped is emitted in place of Started and Finished for a task which is
+	// not run because one of its dependencies failed
+	Skipped Type = "skipped"
+	// SpanStarted is emitted when a task starts a step, like building an image
+	// or waiting for a container to exit
+	SpanStarted Type = "span-started"
+	// SpanFinished is emitted when a step of a task is complete
+	SpanFinished Type = "span-finished"
+)
+
+// Event is a point in the lifecycle of a task
+type Event struct {
+	Type Type
+	Task task.Name
+	Time time.Time
+	// Span is the name of the step of the task for SpanStarted and
+	// SpanFinished events
+	Span string
+	// Reason is the reason a task is stale, fresh, or skipped
+	Reason string
+	// Duration is the time a Finished task took to run
+	Duration time.Duration
+	// Err is the error from a Finished task
+	Err error
+	// Modified is true when a Finished task modified its resource
+	Modified bool
+}
+
+type jsonEvent struct {
+	Type     Type      `json:"type"`
+	Task     string    `json:"task"`
+	Time     time.Time `json:"time"`
+	Span     string    `json:"span,omitempty"`
+	Reason   string    `json:"reason,omitempty"`
+	Duration float64   `json:"duration,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Modified bool      `json:"modified,omitempty"`
+}
+
+// MarshalJSON encodes the event with the duration in seconds
+func (e Event) MarshalJSON() ([]byte, error) {
+	event := jsonEvent{
+		Type:     e.Type,
+		Task:     e.Task.Name(),
+		Time:     e.Time,
+		Span:     e.Span,
+		Reason:   e.Reason,
+		Duration: e.Duration.Seconds(),
+		Modified: e.Modified,
+	}
+	if e.Err != nil {
+		event.Error = e.Err.Error()
+	}
+	return json.Marshal(event)
+}
+
+// NewEvent returns an Event of type for the task
+func NewEvent(eventType Type, name task.Name) Event {
+	return Event{Type: eventType, Task: name}
+}
+
+// NewSpanEvent returns a SpanStarted or SpanFinished event for a step of the
+// task
+func NewSpanEvent(eventType Type, name task.Name, span string) Event {
+	return Event{Type: eventType, Task: name, Span: span}
+}
+
+// NewStalenessEvent returns a Stale or Fresh event with the reason
+func NewStalenessEvent(name task.Name, stale bool, reason string) Event {
+	eventType := Fresh
+	if stale {
+		eventType = Stale
+	}
+	return Event{Type: eventType, Task: name, Reason: reason}
+}
+
+// NewFinishedEvent returns a Finished event for the task
+func NewFinishedEvent(
+	name task.Name,
+	duration time.Duration,
+	modified bool,
+	err error,
+) Event {
+	return Event{
+		Type:     Finished,
+		Task:     name,
+		Duration: duration,
+		Modified: modified,
+		Err:      err,
+	}
+}
+
+// Listener receives events
+type Listener interface {
+	Handle(Event)
+}
+
+// ListenerFunc is a function which implements Listener
+type ListenerFunc func(Event)
+
+// Handle calls the function with the event
+func (f ListenerFunc) Handle(event Event) {
+	f(event)
+}
+
+// Emitter sends events to listeners. Events are sent to listeners one at a
+// time, so listeners do not need to be safe for concurrent use.
+type Emitter struct {
+	lock      sync.Mutex
+	listeners []Listener
+}
+
+// NewEmitter returns a new Emitter which sends events to listeners
+func NewEmitter(listeners ...Listener) *Emitter {
+	return &Emitter{listeners: listeners}
+}
+
+// Emit sends the event to every listener. The time of the event is set to
+// the current time if it is not already set.
+func (e *Emitter) Emit(event Event) {
+	if e == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	for _, listener := range e.listeners {
+		listener.Handle(event)
+	}
+}
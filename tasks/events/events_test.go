@@ -0,0 +1,57 @@
+package events
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks/task"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+type recorder struct {
+	events []Event
+}
+
+func (r *recorder) Handle(event Event) {
+	r.events = append(r.events, event)
+}
+
+func TestEmitterSetsTime(t *testing.T) {
+	listener := &recorder{}
+	emitter := NewEmitter(listener)
+	emitter.Emit(NewEvent(Started, task.NewDefaultName("test", "run")))
+
+	assert.Assert(t, is.Len(listener.events, 1))
+	assert.Check(t, !listener.events[0].Time.IsZero())
+}
+
+func TestNilEmitter(t *testing.T) {
+	var emitter *Emitter
+	emitter.Emit(NewEvent(Started, task.NewDefaultName("test", "run")))
+}
+
+func TestJSONWriter(t *testing.T) {
+	out := new(bytes.Buffer)
+	writer := NewJSONWriter(out, logging.Log)
+	name := task.NewDefaultName("test", "run")
+	eventTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	stale := NewStalenessEvent(name, true, "artifact older than sources")
+	stale.Time = eventTime
+	writer.Handle(stale)
+
+	finished := NewFinishedEvent(name, 1500*time.Millisecond, false, fmt.Errorf("exit 1"))
+	finished.Time = eventTime
+	writer.Handle(finished)
+
+	expected := `{"type":"stale","task":"test:run","time":"2020-01-02T03:04:05Z",` +
+		`"reason":"artifact older than sources"}
+{"type":"finished","task":"test:run","time":"2020-01-02T03:04:05Z",` +
+		`"duration":1.5,"error":"exit 1"}
+`
+	assert.Check(t, is.Equal(expected, out.String()))
+}
@@ -0,0 +1,25 @@
+package events
+
+import (
+	"encoding/json"
+	"io"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type jsonWriter struct {
+	encoder *json.Encoder
+	logger  *log.Logger
+}
+
+// NewJSONWriter returns a Listener which writes each event to out as a line of
+// JSON. Errors writing an event are logged to logger.
+func NewJSONWriter(out io.Writer, logger *log.Logger) Listener {
+	return &jsonWriter{encoder: json.NewEncoder(out), logger: logger}
+}
+
+func (w *jsonWriter) Handle(event Event) {
+	if err := w.encoder.Encode(event); err != nil {
+		w.logger.Warnf("Failed to write event: %s", err)
+	}
+}
@@ -0,0 +1,140 @@
+package tasks
+
+import (
+	"fmt"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/task"
+)
+
+// GraphNode is a task in the dependency graph of a run
+type GraphNode struct {
+	Name         string   `json:"name"`
+	Resource     string   `json:"resource"`
+	Type         string   `json:"type"`
+	Dependencies []string `json:"dependencies"`
+	// Hooks are the tasks run around the task, or nil if the task has no hooks
+	Hooks *GraphHooks `json:"hooks,omitempty"`
+}
+
+// GraphHooks are the names of the tasks run by each hook of a task
+type GraphHooks struct {
+	Before    []string `json:"before,omitempty"`
+	After     []string `json:"after,omitempty"`
+	OnFailure []string `json:"on-failure,omitempty"`
+}
+
+// Graph returns the dependency graph of the tasks in options. Nodes are
+// returned in the order the tasks would run, and include the tasks run by
+// hooks. If no tasks are named, and the config has no default task, the graph
+// includes every resource.
+func Graph(options RunOptions) ([]GraphNode, error) {
+	options.Tasks = getNames(options)
+	if len(options.Tasks) == 0 {
+		options.Tasks = options.Config.Sorted()
+	}
+
+	tasks, err := collectTasks(options)
+	if err != nil {
+		return nil, err
+	}
+
+	builder := &graphBuilder{tasks: tasks, added: make(map[string]bool)}
+	if err := builder.addNodes(newTaskGraph(tasks)); err != nil {
+		return nil, err
+	}
+	return builder.graph, nil
+}
+
+// graphBuilder adds nodes to a graph in the order the tasks would run. The
+// tasks of the hooks of a task are added around the task, unless they are
+// already in the graph.
+type graphBuilder struct {
+	tasks *TaskCollection
+	graph []GraphNode
+	added map[string]bool
+}
+
+func (b *graphBuilder) addNodes(nodes []*taskNode) error {
+	for _, node := range nodes {
+		if err := b.addNode(node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *graphBuilder) addNode(node *taskNode) error {
+	name := node.config.Name()
+	if b.added[name.Name()] {
+		return nil
+	}
+	b.added[name.Name()] = true
+
+	resType, err := resourceType(node.config.Resource())
+	if err != nil {
+		return err
+	}
+	graphNode := GraphNode{
+		Name:         name.Name(),
+		Resource:     name.Resource(),
+		Type:         resType,
+		Dependencies: []string{},
+	}
+	for _, dep := range node.deps {
+		graphNode.Dependencies = append(graphNode.Dependencies, dep.config.Name().Name())
+	}
+
+	hooks := b.tasks.hooksFor(name)
+	if hooks == nil {
+		b.graph = append(b.graph, graphNode)
+		return nil
+	}
+	resourceHooks := config.ResourceHooks(node.config.Resource())
+	graphNode.Hooks = &GraphHooks{
+		Before:    hookNames(hooks.before, resourceHooks.Before),
+		After:     hookNames(hooks.after, resourceHooks.After),
+		OnFailure: hookNames(hooks.onFailure, resourceHooks.OnFailure),
+	}
+	if err := b.addNodes(newTaskGraph(hooks.before)); err != nil {
+		return err
+	}
+	b.graph = append(b.graph, graphNode)
+	if err := b.addNodes(newTaskGraph(hooks.after)); err != nil {
+		return err
+	}
+	return b.addNodes(newTaskGraph(hooks.onFailure))
+}
+
+// hookNames returns the full name of each task listed in a hook
+func hookNames(hook *TaskCollection, names []string) []string {
+	var taskNames []string
+	for _, name := range names {
+		if taskConfig := hook.Get(task.ParseName(name)); taskConfig != nil {
+			taskNames = append(taskNames, taskConfig.Name().Name())
+		}
+	}
+	return taskNames
+}
+
+// resourceType returns the name of the type of resource used in the config file
+func resourceType(resource config.Resource) (string, error) {
+	switch res := resource.(type) {
+	case *config.ImageConfig:
+		return "image", nil
+	case *config.JobConfig:
+		return "job", nil
+	case *config.MountConfig:
+		return "mount", nil
+	case *config.AliasConfig:
+		return "alias", nil
+	case *config.EnvConfig:
+		return "env", nil
+	case *config.ComposeConfig:
+		return "compose", nil
+	case *config.PluginConfig:
+		return res.Type, nil
+	default:
+		return "", fmt.Errorf("unexpected config type %T", resource)
+	}
+}
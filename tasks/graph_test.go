@@ -0,0 +1,138 @@
+package tasks
+
+import (
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	testconfig "github.com/dnephin/dobi/internal/test/config"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestGraph(t *testing.T) {
+	options := RunOptions{
+		Config: &config.Config{
+			Resources: map[string]config.Resource{
+				"builder": &config.ImageConfig{
+					Image:      "builder",
+					Context:    ".",
+					Dockerfile: "Dockerfile",
+				},
+				"source": &config.MountConfig{Bind: ".", Path: "/code"},
+				"test": &config.JobConfig{
+					Use:    "builder",
+					Mounts: []string{"source"},
+				},
+				"all": aliasWithDeps([]string{"test", "builder:build"}),
+			},
+			Meta: &config.MetaConfig{},
+		},
+		Tasks: []string{"all"},
+	}
+
+	graph, err := Graph(options)
+	assert.NilError(t, err)
+	expected := []GraphNode{
+		{Name: "builder:build", Resource: "builder", Type: "image", Dependencies: []string{}},
+		{Name: "source:create", Resource: "source", Type: "mount", Dependencies: []string{}},
+		{
+			Name:         "test:run",
+			Resource:     "test",
+			Type:         "job",
+			Dependencies: []string{"builder:build", "source:create"},
+		},
+		{
+			Name:         "all:run",
+			Resource:     "all",
+			Type:         "alias",
+			Dependencies: []string{"test:run", "builder:build"},
+		},
+	}
+	assert.DeepEqual(t, expected, graph)
+}
+
+func TestGraphIncludesHooks(t *testing.T) {
+	test := &config.AliasConfig{Tasks: []string{}}
+	test.Hooks = config.HooksConfig{
+		Before:    []string{"db"},
+		OnFailure: []string{"logs"},
+	}
+	options := RunOptions{
+		Config: &config.Config{
+			Resources: map[string]config.Resource{
+				"test": test,
+				"db":   aliasWithDeps([]string{}),
+				"logs": aliasWithDeps([]string{"db"}),
+			},
+			Meta: &config.MetaConfig{},
+		},
+		Tasks: []string{"test"},
+	}
+
+	graph, err := Graph(options)
+	assert.NilError(t, err)
+	expected := []GraphNode{
+		{Name: "db:run", Resource: "db", Type: "alias", Dependencies: []string{}},
+		{
+			Name:         "test:run",
+			Resource:     "test",
+			Type:         "alias",
+			Dependencies: []string{},
+			Hooks: &GraphHooks{
+				Before:    []string{"db:run"},
+				OnFailure: []string{"logs:run"},
+			},
+		},
+		{
+			Name:         "logs:run",
+			Resource:     "logs",
+			Type:         "alias",
+			Dependencies: []string{"db:run"},
+		},
+	}
+	assert.DeepEqual(t, expected, graph)
+}
+
+func TestGraphWithUnexpectedResourceType(t *testing.T) {
+	options := RunOptions{
+		Config: &config.Config{
+			Resources: map[string]config.Resource{"fake": &testconfig.FakeResource{}},
+			Meta:      &config.MetaConfig{},
+		},
+		Tasks: []string{"fake"},
+	}
+
+	_, err := Graph(options)
+	assert.Check(t, is.Error(err, "unexpected config type *config.FakeResource"))
+}
+
+// The nodes of the graph are named by task.Name, so the default action of a
+// resource is named after the action, and is the same node as the task named
+// with the action.
+func TestGraphNamesDefaultActions(t *testing.T) {
+	options := RunOptions{
+		Config: &config.Config{
+			Resources: map[string]config.Resource{
+				"builder": &config.ImageConfig{Image: "builder", Context: ".", Dockerfile: "Dockerfile"},
+				"source":  &config.MountConfig{Bind: ".", Path: "/code"},
+				"test":    &config.JobConfig{Use: "builder", Mounts: []string{"source"}},
+			},
+			Meta: &config.MetaConfig{},
+		},
+		Tasks: []string{"source", "test", "test:run", "source:create"},
+	}
+
+	graph, err := Graph(options)
+	assert.NilError(t, err)
+	expected := []GraphNode{
+		{Name: "source:create", Resource: "source", Type: "mount", Dependencies: []string{}},
+		{Name: "builder:build", Resource: "builder", Type: "image", Dependencies: []string{}},
+		{
+			Name:         "test:run",
+			Resource:     "test",
+			Type:         "job",
+			Dependencies: []string{"builder:build", "source:create"},
+		},
+	}
+	assert.DeepEqual(t, expected, graph)
+}
@@ -27,34 +27,37 @@ func GetTaskConfig(name, action string, conf *config.ImageConfig) (types.TaskCon
 		taskName,
 		conf,
 		deps(conf, imageAction.dependencies),
-		NewTask(imageAction.run),
+		NewTask(imageAction.run, imageAction.isStale),
 	), nil
 }
 
 type runFunc func(*context.ExecuteContext, *Task, bool) (bool, error)
 
+type staleFunc func(*context.ExecuteContext, *Task) (types.Staleness, error)
+
 type action struct {
 	name         string
 	run          runFunc
+	isStale      staleFunc
 	dependencies []string
 }
 
-func newAction(name string, run runFunc, deps []string) (action, error) {
-	return action{name: name, run: run, dependencies: deps}, nil
+func newAction(name string, run runFunc, isStale staleFunc, deps []string) (action, error) {
+	return action{name: name, run: run, isStale: isStale, dependencies: deps}, nil
 }
 
 func getAction(name string, task string) (action, error) {
 	switch name {
 	case "build":
-		return newAction("build", RunBuild, nil)
+		return newAction("build", RunBuild, buildIsStale, nil)
 	case "pull":
-		return newAction("pull", RunPull, nil)
+		return newAction("pull", RunPull, pullIsStale, nil)
 	case "push":
-		return newAction("push", RunPush, imageDeps(task, "tag"))
+		return newAction("push", RunPush, nil, imageDeps(task, "tag"))
 	case "tag":
-		return newAction("tag", RunTag, imageDeps(task, "build"))
+		return newAction("tag", RunTag, nil, imageDeps(task, "build"))
 	case "remove", "rm":
-		return newAction("remove", RunRemove, nil)
+		return newAction("remove", RunRemove, nil, nil)
 	default:
 		return action{}, fmt.Errorf("invalid image action %q for task %q", name, task)
 	}
@@ -82,8 +85,13 @@ func deps(conf config.Resource, deps []string) func() []string {
 }
 
 // NewTask creates a new Task object
-func NewTask(runFunc runFunc) func(task.Name, config.Resource) types.Task {
+func NewTask(runFunc runFunc, isStale staleFunc) func(task.Name, config.Resource) types.Task {
 	return func(name task.Name, conf config.Resource) types.Task {
-		return &Task{name: name, config: conf.(*config.ImageConfig), runFunc: runFunc}
+		return &Task{
+			name:    name,
+			config:  conf.(*config.ImageConfig),
+			runFunc: runFunc,
+			isStale: isStale,
+		}
 	}
 }
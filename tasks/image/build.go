@@ -1,14 +1,15 @@
 package image
 
 import (
+	"fmt"
 	"io"
 	"io/ioutil"
-	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/dnephin/dobi/config"
 	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/types"
 	"github.com/dnephin/dobi/utils/fs"
 	"github.com/docker/cli/cli/command/image/build"
 	"github.com/docker/docker/pkg/archive"
@@ -18,23 +19,28 @@ import (
 
 // RunBuild builds an image if it is out of date
 func RunBuild(ctx *context.ExecuteContext, t *Task, hasModifiedDeps bool) (bool, error) {
-	if !hasModifiedDeps {
-		stale, err := buildIsStale(ctx, t)
-		switch {
-		case err != nil:
-			return false, err
-		case !stale:
-			t.logger().Info("is fresh")
-			return false, nil
-		}
+	staleness, err := types.CheckStaleness(ctx, t.name, hasModifiedDeps,
+		func() (types.Staleness, error) { return buildIsStale(ctx, t) })
+	switch {
+	case err != nil:
+		return false, err
+	case !staleness.Stale:
+		t.logger(ctx).Info("is fresh")
+		return false, nil
 	}
-	t.logger().Debug("is stale")
+	t.logger(ctx).Debug(staleness.Reason)
+	t.logger(ctx).Debug("is stale")
 
 	if !t.config.IsBuildable() {
 		return false, errors.Errorf(
 			"%s is not buildable, missing required fields", t.name.Resource())
 	}
 
+	digest, err := buildDigest(ctx, t)
+	if err != nil {
+		return false, err
+	}
+
 	if err := buildImage(ctx, t); err != nil {
 		return false, err
 	}
@@ -44,64 +50,145 @@ func RunBuild(ctx *context.ExecuteContext, t *Task, hasModifiedDeps bool) (bool,
 		return false, err
 	}
 
-	record := imageModifiedRecord{ImageID: image.ID}
+	record := imageModifiedRecord{ImageID: image.ID, ContextDigest: digest}
 	if err := updateImageRecord(recordPath(ctx, t.config), record); err != nil {
-		t.logger().Warnf("Failed to update image record: %s", err)
+		t.logger(ctx).Warnf("Failed to update image record: %s", err)
 	}
-	t.logger().Info("Created")
+	t.logger(ctx).Info("Created")
 	return true, nil
 }
 
+// buildIsStale checks if the image is older than the files in the build
+// context. The details of the Staleness include the ID of the image, the ID
+// from the image record, and the newest file in the context.
 // TODO: this cyclo problem should be fixed
 // nolint: gocyclo
-func buildIsStale(ctx *context.ExecuteContext, t *Task) (bool, error) {
+func buildIsStale(ctx *context.ExecuteContext, t *Task) (types.Staleness, error) {
 	image, err := GetImage(ctx, t.config)
 	switch err {
 	case docker.ErrNoSuchImage:
-		t.logger().Debug("Image does not exist")
-		return true, nil
+		return types.Stale("Image does not exist").Explain(
+			fmt.Sprintf("image: %s not found", GetImageName(ctx, t.config))), nil
 	case nil:
 	default:
-		return true, err
+		return types.Stale("failed to inspect image"), err
+	}
+	details := []string{fmt.Sprintf("image: %s ID %s created %s",
+		GetImageName(ctx, t.config), shortID(image.ID), types.FormatTime(image.Created))}
+
+	if t.config.Freshness == config.FreshnessHash {
+		staleness, err := buildIsStaleByDigest(ctx, t, image)
+		staleness.Details = append(details, staleness.Details...)
+		return staleness, err
+	}
+
+	path, mtime, err := fs.NewestFile(contextSearch(ctx, t))
+	if err != nil {
+		t.logger(ctx).Warnf("Failed to get last modified time of context.")
+		return types.Stale("failed to get last modified time of context"), err
+	}
+	details = append(details, types.FileDetail("newest context file", path, mtime))
+
+	record, err := getImageRecord(recordPath(ctx, t.config))
+	if err != nil {
+		t.logger(ctx).Warnf("Failed to get image record: %s", err)
+		details = append(details, "image record: does not exist")
+		if image.Created.Before(mtime) {
+			return types.Stale("Image older than context").Explain(details...), nil
+		}
+		return types.Fresh("Image newer than context").Explain(details...), nil
+	}
+	details = append(details, fmt.Sprintf("image record: ID %s written %s",
+		shortID(record.ImageID), types.FormatTime(record.Info.ModTime())))
+
+	if image.ID != record.ImageID || record.Info.ModTime().Before(mtime) {
+		return types.Stale("Image record older than context").Explain(details...), nil
 	}
+	return types.Fresh("Image record newer than context").Explain(details...), nil
+}
 
-	paths := []string{t.config.Context}
+// shortID returns the ID of an image shortened for display
+func shortID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// buildIsStaleByDigest compares the digest of the build context to the digest
+// in the image record from the last build
+func buildIsStaleByDigest(
+	ctx *context.ExecuteContext,
+	t *Task,
+	image *docker.Image,
+) (types.Staleness, error) {
+	digest, err := contextDigest(ctx, t)
+	if err != nil {
+		t.logger(ctx).Warnf("Failed to get digest of context.")
+		return types.Stale("failed to get digest of context"), err
+	}
+	t.digest = digest
+	details := []string{"context digest: " + shortID(digest)}
+
+	record, err := getImageRecord(recordPath(ctx, t.config))
+	if err != nil {
+		t.logger(ctx).Warnf("Failed to get image record: %s", err)
+		details = append(details, "image record: does not exist")
+		return types.Stale("Image record does not exist").Explain(details...), nil
+	}
+	details = append(details,
+		fmt.Sprintf("image record: ID %s context digest %s",
+			shortID(record.ImageID), shortID(record.ContextDigest)))
+	switch {
+	case image.ID != record.ImageID:
+		return types.Stale("Image does not match image record").Explain(details...), nil
+	case digest != record.ContextDigest:
+		return types.Stale("Context digest changed").Explain(details...), nil
+	}
+	return types.Fresh("Context digest unchanged").Explain(details...), nil
+}
+
+// contextSearch returns the search for the files in the build context, which
+// excludes the files from the .dockerignore in the context
+func contextSearch(ctx *context.ExecuteContext, t *Task) *fs.LastModifiedSearch {
+	contextDir := absPath(t.config.Context, ctx.WorkingDir)
+	paths := []string{contextDir}
 	// TODO: polymorphic config for different types of images
 	if t.config.Steps != "" && ctx.ConfigFile != "" {
 		paths = append(paths, ctx.ConfigFile)
 	}
 
-	excludes, err := build.ReadDockerignore(t.config.Context)
+	excludes, err := build.ReadDockerignore(contextDir)
 	if err != nil {
-		t.logger().Warnf("Failed to read .dockerignore file.")
+		t.logger(ctx).Warnf("Failed to read .dockerignore file.")
 	}
 	excludes = append(excludes, ".dobi")
 
-	mtime, err := fs.LastModified(&fs.LastModifiedSearch{
-		Root:     absPath(ctx.WorkingDir, t.config.Context),
+	return &fs.LastModifiedSearch{
+		Root:     contextDir,
 		Excludes: excludes,
 		Paths:    paths,
-	})
-	if err != nil {
-		t.logger().Warnf("Failed to get last modified time of context.")
-		return true, err
 	}
+}
 
-	record, err := getImageRecord(recordPath(ctx, t.config))
-	if err != nil {
-		t.logger().Warnf("Failed to get image record: %s", err)
-		if image.Created.Before(mtime) {
-			t.logger().Debug("Image older than context")
-			return true, nil
-		}
-		return false, nil
+// contextDigest returns the digest of the build context if the image uses hash
+// freshness, otherwise it returns an empty string
+func contextDigest(ctx *context.ExecuteContext, t *Task) (string, error) {
+	if t.config.Freshness != config.FreshnessHash {
+		return "", nil
 	}
+	return fs.Digest(contextSearch(ctx, t))
+}
 
-	if image.ID != record.ImageID || record.Info.ModTime().Before(mtime) {
-		t.logger().Debug("Image record older than context")
-		return true, nil
+// buildDigest returns the digest of the build context for this build. The
+// digest from the staleness check is used when there was one, so the context
+// is only read once.
+func buildDigest(ctx *context.ExecuteContext, t *Task) (string, error) {
+	if t.digest != "" {
+		return t.digest, nil
 	}
-	return false, nil
+	return contextDigest(ctx, t)
 }
 
 func absPath(path string, wd string) string {
@@ -112,6 +199,7 @@ func absPath(path string, wd string) string {
 }
 
 func buildImage(ctx *context.ExecuteContext, t *Task) error {
+	defer ctx.Span(t.name, "build image")()
 	var err error
 	if t.config.Steps != "" {
 		err = t.buildImageFromSteps(ctx)
@@ -130,7 +218,7 @@ func buildImage(ctx *context.ExecuteContext, t *Task) error {
 }
 
 func (t *Task) buildImageFromDockerfile(ctx *context.ExecuteContext) error {
-	return Stream(os.Stdout, func(out io.Writer) error {
+	return Stream(ctx.Stdout, func(out io.Writer) error {
 		opts := t.commonBuildImageOptions(ctx, out)
 		opts.Dockerfile = t.config.Dockerfile
 		opts.ContextDir = t.config.Context
@@ -154,6 +242,7 @@ func (t *Task) commonBuildImageOptions(
 		RawJSONStream:  true,
 		SuppressOutput: ctx.Settings.Quiet,
 		AuthConfigs:    ctx.GetAuthConfigs(),
+		Context:        ctx.Context,
 	}
 }
 
@@ -170,7 +259,7 @@ func (t *Task) buildImageFromSteps(ctx *context.ExecuteContext) error {
 	if err != nil {
 		return err
 	}
-	return Stream(os.Stdout, func(out io.Writer) error {
+	return Stream(ctx.Stdout, func(out io.Writer) error {
 		opts := t.commonBuildImageOptions(ctx, out)
 		opts.InputStream = buildContext
 		opts.Dockerfile = dockerfile
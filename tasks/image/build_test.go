@@ -0,0 +1,71 @@
+package image
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks/context"
+	docker "github.com/fsouza/go-dockerclient"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestContextDigestIgnoresFilesInDockerignore(t *testing.T) {
+	dir := fs.NewDir(t, "image-context",
+		fs.WithDir("context",
+			fs.WithFile(".dockerignore", "ignored.txt\n"),
+			fs.WithFile("Dockerfile", "FROM alpine\n"),
+			fs.WithFile("ignored.txt", "one"),
+			fs.WithFile("main.go", "package main\n")))
+	defer dir.Remove()
+
+	ctx := &context.ExecuteContext{WorkingDir: dir.Path(), Logger: logging.Log}
+	task := &Task{config: &config.ImageConfig{
+		Context:   "context",
+		Freshness: config.FreshnessHash,
+	}}
+	digest := func() string {
+		digest, err := contextDigest(ctx, task)
+		assert.NilError(t, err)
+		return digest
+	}
+
+	original := digest()
+	writeFile(t, dir.Join("context", "ignored.txt"), "two")
+	assert.Equal(t, original, digest())
+
+	writeFile(t, dir.Join("context", "main.go"), "package main // changed\n")
+	assert.Assert(t, original != digest())
+}
+
+func TestBuildDigestUsesDigestFromStalenessCheck(t *testing.T) {
+	dir := fs.NewDir(t, "image-context",
+		fs.WithDir("context",
+			fs.WithFile("Dockerfile", "FROM alpine\n"),
+			fs.WithFile("main.go", "package main\n")))
+	defer dir.Remove()
+
+	ctx := &context.ExecuteContext{WorkingDir: dir.Path(), Logger: logging.Log}
+	task := &Task{config: &config.ImageConfig{
+		Image:     "example",
+		Tags:      []string{"latest"},
+		Context:   "context",
+		Freshness: config.FreshnessHash,
+	}}
+	_, err := buildIsStaleByDigest(ctx, task, &docker.Image{ID: "image-id"})
+	assert.NilError(t, err)
+	checked, err := contextDigest(ctx, task)
+	assert.NilError(t, err)
+
+	writeFile(t, dir.Join("context", "main.go"), "package main // changed\n")
+	digest, err := buildDigest(ctx, task)
+	assert.NilError(t, err)
+	assert.Equal(t, checked, digest)
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	assert.NilError(t, ioutil.WriteFile(path, []byte(content), 0644))
+}
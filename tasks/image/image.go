@@ -21,6 +21,9 @@ type Task struct {
 	name    task.Name
 	config  *config.ImageConfig
 	runFunc runFunc
+	isStale staleFunc
+	// digest is the digest of the build context from the staleness check
+	digest string
 }
 
 // Name returns the name of the task
@@ -28,8 +31,8 @@ func (t *Task) Name() task.Name {
 	return t.name
 }
 
-func (t *Task) logger() *log.Entry {
-	return logging.ForTask(t)
+func (t *Task) logger(ctx *context.ExecuteContext) *log.Entry {
+	return logging.ForTask(ctx.Logger, t)
 }
 
 // Repr formats the task for logging
@@ -39,7 +42,26 @@ func (t *Task) Repr() string {
 
 // Run builds or pulls an image if it is out of date
 func (t *Task) Run(ctx *context.ExecuteContext, depsModified bool) (bool, error) {
-	return t.runFunc(ctx, t, depsModified)
+	ctx, cancel := ctx.WithTimeout(t.config.Timeout.Value())
+	defer cancel()
+	if t.isStale == nil {
+		// the actions which check if they are stale send the event from runFunc
+		if _, err := types.CheckStaleness(ctx, t.name, depsModified,
+			func() (types.Staleness, error) { return t.IsStale(ctx) }); err != nil {
+			return false, err
+		}
+	}
+	modified, err := t.runFunc(ctx, t, depsModified)
+	return modified, ctx.TimeoutError(err)
+}
+
+// IsStale checks if the action needs to run. Actions which do not check
+// for changes are always stale.
+func (t *Task) IsStale(ctx *context.ExecuteContext) (types.Staleness, error) {
+	if t.isStale == nil {
+		return types.Stale("always runs"), nil
+	}
+	return t.isStale(ctx, t)
 }
 
 // ForEachTag runs a function for each tag
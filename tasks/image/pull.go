@@ -2,22 +2,24 @@ package image
 
 import (
 	"io"
-	"os"
 	"time"
 
 	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/retry"
+	"github.com/dnephin/dobi/tasks/types"
 	docker "github.com/fsouza/go-dockerclient"
 )
 
 // RunPull builds or pulls an image if it is out of date
 func RunPull(ctx *context.ExecuteContext, t *Task, _ bool) (bool, error) {
-	record, err := getImageRecord(recordPath(ctx, t.config))
+	staleness, err := types.CheckStaleness(ctx, t.name, false,
+		func() (types.Staleness, error) { return pullIsStale(ctx, t) })
 	switch {
-	case !t.config.Pull.Required(record.LastPull):
-		t.logger().Debugf("Pull not required")
-		return false, nil
 	case err != nil:
-		t.logger().Warnf("Failed to get image record: %s", err)
+		return false, err
+	case !staleness.Stale:
+		t.logger(ctx).Debugf("Pull not required")
+		return false, nil
 	}
 
 	pullTag := func(tag string) error {
@@ -31,16 +33,35 @@ func RunPull(ctx *context.ExecuteContext, t *Task, _ bool) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	record = imageModifiedRecord{LastPull: now(), ImageID: image.ID}
+	record := imageModifiedRecord{LastPull: now(), ImageID: image.ID}
 
 	if err := updateImageRecord(recordPath(ctx, t.config), record); err != nil {
-		t.logger().Warnf("Failed to update image record: %s", err)
+		t.logger(ctx).Warnf("Failed to update image record: %s", err)
 	}
 
-	t.logger().Info("Pulled")
+	t.logger(ctx).Info("Pulled")
 	return true, nil
 }
 
+// pullIsStale checks the last pull time from the image record against the pull
+// policy of the image.
+func pullIsStale(ctx *context.ExecuteContext, t *Task) (types.Staleness, error) {
+	record, err := getImageRecord(recordPath(ctx, t.config))
+	details := []string{"pull policy: " + t.config.Pull.String()}
+	if record.LastPull != nil {
+		details = append(details, "last pull: "+types.FormatTime(*record.LastPull))
+	} else {
+		details = append(details, "last pull: never")
+	}
+	switch {
+	case !t.config.Pull.Required(record.LastPull):
+		return types.Fresh("Pull not required").Explain(details...), nil
+	case err != nil:
+		t.logger(ctx).Warnf("Failed to get image record: %s", err)
+	}
+	return types.Stale("Pull required").Explain(details...), nil
+}
+
 func now() *time.Time {
 	now := time.Now()
 	return &now
@@ -49,13 +70,15 @@ func now() *time.Time {
 func pullImage(ctx *context.ExecuteContext, t *Task, imageTag string) error {
 	registry := parseAuthRepo(t.config.Image)
 	repo, tag := docker.ParseRepositoryTag(imageTag)
-	return Stream(os.Stdout, func(out io.Writer) error {
-		return ctx.Client.PullImage(docker.PullImageOptions{
-			Repository:    repo,
-			Tag:           tag,
-			OutputStream:  out,
-			RawJSONStream: true,
-			// TODO: timeout
-		}, ctx.GetAuthConfig(registry))
+	return retry.Do(ctx.Context, t.logger(ctx), t.config.Retry, registryRetryable, func() error {
+		return Stream(ctx.Stdout, func(out io.Writer) error {
+			return ctx.Client.PullImage(docker.PullImageOptions{
+				Repository:    repo,
+				Tag:           tag,
+				OutputStream:  out,
+				RawJSONStream: true,
+				Context:       ctx.Context,
+			}, ctx.GetAuthConfig(registry))
+		})
 	})
 }
@@ -2,32 +2,34 @@ package image
 
 import (
 	"io"
-	"os"
 
 	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/retry"
 	docker "github.com/fsouza/go-dockerclient"
 )
 
 // RunPush pushes an image to the registry
 func RunPush(ctx *context.ExecuteContext, t *Task, _ bool) (bool, error) {
 	pushTag := func(tag string) error {
-		return pushImage(ctx, tag)
+		return pushImage(ctx, t, tag)
 	}
 	if err := t.ForEachRemoteTag(ctx, pushTag); err != nil {
 		return false, err
 	}
-	t.logger().Info("Pushed")
+	t.logger(ctx).Info("Pushed")
 	return true, nil
 }
 
-func pushImage(ctx *context.ExecuteContext, tag string) error {
+func pushImage(ctx *context.ExecuteContext, t *Task, tag string) error {
 	repo := parseAuthRepo(tag)
-	return Stream(os.Stdout, func(out io.Writer) error {
-		return ctx.Client.PushImage(docker.PushImageOptions{
-			Name:          tag,
-			OutputStream:  out,
-			RawJSONStream: true,
-			// TODO: timeout
-		}, ctx.GetAuthConfig(repo))
+	return retry.Do(ctx.Context, t.logger(ctx), t.config.Retry, registryRetryable, func() error {
+		return Stream(ctx.Stdout, func(out io.Writer) error {
+			return ctx.Client.PushImage(docker.PushImageOptions{
+				Name:          tag,
+				OutputStream:  out,
+				RawJSONStream: true,
+				Context:       ctx.Context,
+			}, ctx.GetAuthConfig(repo))
+		})
 	})
 }
@@ -19,8 +19,11 @@ const (
 
 type imageModifiedRecord struct {
 	ImageID  string
-	LastPull *time.Time  `yaml:",omitempty"`
-	Info     os.FileInfo `yaml:",omitempty"`
+	LastPull *time.Time `yaml:",omitempty"`
+	// ContextDigest is the digest of the build context, used by images with
+	// hash freshness
+	ContextDigest string      `yaml:",omitempty"`
+	Info          os.FileInfo `yaml:",omitempty"`
 }
 
 func updateImageRecord(path string, record imageModifiedRecord) error {
@@ -2,13 +2,15 @@ package image
 
 import (
 	"github.com/dnephin/dobi/tasks/context"
+	docker "github.com/fsouza/go-dockerclient"
 )
 
 // RunRemove removes an image
 func RunRemove(ctx *context.ExecuteContext, t *Task, _ bool) (bool, error) {
+	removeOptions := docker.RemoveImageOptions{Context: ctx.Context}
 	removeTag := func(tag string) error {
-		if err := ctx.Client.RemoveImage(tag); err != nil {
-			t.logger().Warnf("failed to remove %q: %s", tag, err)
+		if err := ctx.Client.RemoveImageExtended(tag, removeOptions); err != nil {
+			t.logger(ctx).Warnf("failed to remove %q: %s", tag, err)
 		}
 		return nil
 	}
@@ -19,9 +21,9 @@ func RunRemove(ctx *context.ExecuteContext, t *Task, _ bool) (bool, error) {
 
 	// Clear the image record so the .dobi state does not break for "pull once" images
 	if err := updateImageRecord(recordPath(ctx, t.config), imageModifiedRecord{}); err != nil {
-		t.logger().Warnf("Failed to clear image record: %s", err)
+		t.logger(ctx).Warnf("Failed to clear image record: %s", err)
 	}
 
-	t.logger().Info("Removed")
+	t.logger(ctx).Info("Removed")
 	return true, nil
 }
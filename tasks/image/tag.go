@@ -16,7 +16,7 @@ func RunTag(ctx *context.ExecuteContext, t *Task, _ bool) (bool, error) {
 	if err := t.ForEachTag(ctx, tag); err != nil {
 		return false, err
 	}
-	t.logger().Info("Tagged")
+	t.logger(ctx).Info("Tagged")
 	return true, nil
 }
 
@@ -28,9 +28,10 @@ func tagImage(ctx *context.ExecuteContext, config *config.ImageConfig, imageTag
 
 	repo, tag := docker.ParseRepositoryTag(imageTag)
 	err := ctx.Client.TagImage(canonicalImageTag, docker.TagImageOptions{
-		Repo:  repo,
-		Tag:   tag,
-		Force: true,
+		Repo:    repo,
+		Tag:     tag,
+		Force:   true,
+		Context: ctx.Context,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to add tag %q: %s", imageTag, err)
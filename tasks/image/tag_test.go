@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/logging"
 	"github.com/dnephin/dobi/tasks/client"
 	"github.com/dnephin/dobi/tasks/context"
 	docker "github.com/fsouza/go-dockerclient"
@@ -23,6 +24,7 @@ func setupCtxAndConfig(
 	ctx := &context.ExecuteContext{
 		Client:     mockClient,
 		WorkingDir: "/dir",
+		Logger:     logging.Log,
 	}
 	config := &config.ImageConfig{
 		Image: "imagename",
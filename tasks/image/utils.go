@@ -2,11 +2,12 @@ package image
 
 import (
 	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/dnephin/dobi/config"
-	"github.com/dnephin/dobi/logging"
 	"github.com/dnephin/dobi/tasks/context"
+	"github.com/docker/docker/pkg/jsonmessage"
 	docker "github.com/fsouza/go-dockerclient"
 )
 
@@ -14,9 +15,26 @@ const (
 	defaultRepo = "https://index.docker.io/v1/"
 )
 
-// GetImage returns the image created by an image config
+// GetImage returns the image created by an image config. The docker client
+// does not accept a Context to inspect an image, so GetImage stops waiting for
+// the response when the Context is cancelled.
 func GetImage(ctx *context.ExecuteContext, conf *config.ImageConfig) (*docker.Image, error) {
-	return ctx.Client.InspectImage(GetImageName(ctx, conf))
+	type result struct {
+		image *docker.Image
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		image, err := ctx.Client.InspectImage(GetImageName(ctx, conf))
+		done <- result{image: image, err: err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.image, res.err
+	case <-ctx.Context.Done():
+		return nil, ctx.Context.Err()
+	}
 }
 
 // GetImageName returns the image name for an image config
@@ -42,8 +60,47 @@ func parseAuthRepo(image string) string {
 func splitHostname(name string) string {
 	i := strings.IndexRune(name, '/')
 	if i == -1 || (!strings.ContainsAny(name[:i], ".:") && name[:i] != "localhost") {
-		logging.Log.Debugf("Using default registry %q", defaultRepo)
 		return defaultRepo
 	}
 	return name[:i]
 }
+
+// permanentRegistryErrors are the messages of errors from the registry which
+// are not fixed by trying again. Errors in the output of a pull or push only
+// have a message, without the status code.
+var permanentRegistryErrors = []string{
+	"unauthorized",
+	"denied",
+	"not found",
+	"manifest unknown",
+	"does not exist",
+}
+
+// registryRetryable returns false if err is an error from the registry which
+// is not fixed by trying again, like missing credentials, missing permission to
+// the repository, or a missing image. Other errors are retried.
+func registryRetryable(err error) bool {
+	switch err := err.(type) {
+	case *docker.Error:
+		return !isPermanentStatus(err.Status)
+	case *jsonmessage.JSONError:
+		if err.Code != 0 {
+			return !isPermanentStatus(err.Code)
+		}
+		message := strings.ToLower(err.Message)
+		for _, permanent := range permanentRegistryErrors {
+			if strings.Contains(message, permanent) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func isPermanentStatus(status int) bool {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+		return true
+	}
+	return false
+}
@@ -1,8 +1,11 @@
 package image
 
 import (
+	"fmt"
 	"testing"
 
+	"github.com/docker/docker/pkg/jsonmessage"
+	docker "github.com/fsouza/go-dockerclient"
 	"gotest.tools/v3/assert"
 	is "gotest.tools/v3/assert/cmp"
 )
@@ -26,3 +29,25 @@ func TestParseAuthRepoPrivateRepoNoUsername(t *testing.T) {
 	repo := parseAuthRepo("myrepo.net/foo")
 	assert.Check(t, is.Equal(repo, "myrepo.net"))
 }
+
+func TestRegistryRetryable(t *testing.T) {
+	var testcases = []struct {
+		err       error
+		retryable bool
+	}{
+		{err: &docker.Error{Status: 401, Message: "unauthorized"}},
+		{err: &docker.Error{Status: 403}},
+		{err: &docker.Error{Status: 404, Message: "repository does not exist"}},
+		{err: &docker.Error{Status: 500}, retryable: true},
+		{err: &jsonmessage.JSONError{Code: 404}},
+		{err: &jsonmessage.JSONError{Message: "unauthorized: authentication required"}},
+		{err: &jsonmessage.JSONError{Message: "denied: requested access to the resource is denied"}},
+		{err: &jsonmessage.JSONError{Message: "manifest for example:v1 not found"}},
+		{err: &jsonmessage.JSONError{Message: "net/http: TLS handshake timeout"}, retryable: true},
+		{err: fmt.Errorf("connection reset by peer"), retryable: true},
+	}
+	for _, testcase := range testcases {
+		assert.Check(t, is.Equal(testcase.retryable, registryRetryable(testcase.err)),
+			"error: %s", testcase.err)
+	}
+}
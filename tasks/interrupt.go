@@ -0,0 +1,77 @@
+package tasks
+
+import (
+	gocontext "context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrInterrupted is returned by Run when the run is stopped by its Context, or
+// by SIGINT or SIGTERM when the signals are handled
+var ErrInterrupted = errors.New("interrupted")
+
+// ExitCodeInterrupted is the exit code used when a run is interrupted. It is
+// the exit code used by shells for a process stopped by SIGINT.
+const ExitCodeInterrupted = 130
+
+var errInterrupted = errors.New("not started after dobi was interrupted")
+
+// cancelOnInterrupt returns a Context which is cancelled when dobi receives
+// SIGINT or SIGTERM, and a second Context which is cancelled when it receives
+// the signal again. Cancelling the first Context stops any build, pull, push,
+// or compose command which is running, and gives the container of a job time
+// to exit. Cancelling the second Context kills the containers immediately.
+// After the second signal dobi stops handling the signals, so a third signal
+// exits dobi. The returned function stops handling the signals.
+func cancelOnInterrupt(
+	parent gocontext.Context,
+	logger *log.Logger,
+) (gocontext.Context, gocontext.Context, func()) {
+	gctx, cancel := gocontext.WithCancel(parent)
+	kill, cancelKill := gocontext.WithCancel(gocontext.Background())
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case sig := <-signals:
+			logger.Warnf("Received %s, stopping tasks. Send it again to kill them.", sig)
+			cancel()
+		case <-done:
+			return
+		}
+		select {
+		case sig := <-signals:
+			logger.Errorf("Received %s, killing tasks", sig)
+			signal.Stop(signals)
+			cancelKill()
+		case <-done:
+		}
+	}()
+
+	return gctx, kill, func() {
+		signal.Stop(signals)
+		close(done)
+		cancel()
+		cancelKill()
+	}
+}
+
+// interruptedError returns an error which wraps ErrInterrupted when the
+// Context was cancelled, otherwise it returns err
+func interruptedError(gctx gocontext.Context, err error) error {
+	switch {
+	case gctx.Err() == nil:
+		return err
+	case err == nil:
+		return ErrInterrupted
+	default:
+		return fmt.Errorf("%w: %s", ErrInterrupted, err)
+	}
+}
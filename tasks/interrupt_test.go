@@ -0,0 +1,51 @@
+package tasks
+
+import (
+	"bytes"
+	gocontext "context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/dnephin/dobi/tasks/context"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestExecuteTasksAfterInterrupt(t *testing.T) {
+	gctx, cancel := gocontext.WithCancel(gocontext.Background())
+	ran := []string{}
+	run := func(name string) func(*context.ExecuteContext) error {
+		return func(*context.ExecuteContext) error {
+			ran = append(ran, name)
+			cancel()
+			return nil
+		}
+	}
+	tasks := newTestCollection(
+		newFakeTaskConfig("one:run", nil, run("one")),
+		newFakeTaskConfig("two:run", nil, run("two")),
+	)
+	ctx := newTestContext()
+	ctx.Context = gctx
+	ctx.Settings.KeepGoing = true
+
+	err := interruptedError(gctx, executeTasks(ctx, tasks))
+	assert.Check(t, errors.Is(err, ErrInterrupted))
+	assert.Check(t, is.DeepEqual([]string{"one"}, ran))
+	assert.Check(t, is.Contains(ctx.Stdout.(*bytes.Buffer).String(),
+		"two:run  skipped    not started after dobi was interrupted"))
+}
+
+func TestInterruptedError(t *testing.T) {
+	gctx, cancel := gocontext.WithCancel(gocontext.Background())
+	failed := fmt.Errorf("failed to execute task")
+	assert.Check(t, is.Equal(failed, interruptedError(gctx, failed)))
+	assert.Check(t, is.Nil(interruptedError(gctx, nil)))
+
+	cancel()
+	assert.Check(t, is.Equal(ErrInterrupted, interruptedError(gctx, nil)))
+	err := interruptedError(gctx, failed)
+	assert.Check(t, errors.Is(err, ErrInterrupted))
+	assert.Check(t, is.Error(err, "interrupted: failed to execute task"))
+}
@@ -15,7 +15,7 @@ func GetTaskConfig(name, action string, conf *config.JobConfig) (types.TaskConfi
 	switch action {
 	case "", "run":
 		return types.NewTaskConfig(
-			task.NewDefaultName(name, action),
+			task.NewDefaultName(name, "run"),
 			conf,
 			deps(conf),
 			newRunTask), nil
@@ -3,6 +3,8 @@ package job
 import (
 	"testing"
 
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/task"
 	"gotest.tools/v3/assert"
 	is "gotest.tools/v3/assert/cmp"
 )
@@ -17,3 +19,12 @@ func TestParseCaptureInvalid(t *testing.T) {
 	_, err := parseCapture("capture")
 	assert.Check(t, is.ErrorContains(err, "invalid capture format"))
 }
+
+func TestGetTaskConfigNamesDefaultAction(t *testing.T) {
+	for _, action := range []string{"", "run"} {
+		taskConfig, err := GetTaskConfig("test", action, &config.JobConfig{Use: "builder"})
+		assert.NilError(t, err)
+		assert.Check(t, is.Equal("test:run", taskConfig.Name().Name()))
+		assert.Check(t, taskConfig.Name().Equal(task.NewDefaultName("test", "")))
+	}
+}
@@ -12,8 +12,6 @@ import (
 	"strings"
 
 	"github.com/dnephin/dobi/config"
-	"github.com/dnephin/dobi/logging"
-	"github.com/dnephin/dobi/tasks/client"
 	"github.com/dnephin/dobi/tasks/context"
 	"github.com/dnephin/dobi/tasks/image"
 	"github.com/docker/cli/cli/command/image/build"
@@ -28,15 +26,20 @@ func (t *Task) runWithBuildAndCopy(ctx *context.ExecuteContext) error {
 	imageName := fmt.Sprintf("%s:job-%s",
 		ctx.Resources.Image(t.config.Use).Image, name)
 
-	if err := t.buildImageWithMounts(ctx, imageName); err != nil {
+	endSpan := ctx.Span(t.name, "build image")
+	err := t.buildImageWithMounts(ctx, imageName)
+	endSpan()
+	if err != nil {
 		return err
 	}
-	defer removeImage(t.logger(), ctx.Client, imageName)
+	defer removeImage(ctx, t.logger(ctx), imageName)
 
-	defer removeContainerWithLogging(t.logger(), ctx.Client, name)
+	defer removeContainerWithLogging(ctx.Kill, t.logger(ctx), ctx.Client, name)
 	options := t.createOptions(ctx, name, imageName)
 	runErr := t.runContainer(ctx, options)
-	copyErr := copyFilesToHost(t.logger(), ctx, t.config, name)
+	endSpan = ctx.Span(t.name, "copy artifacts")
+	copyErr := copyFilesToHost(t.logger(ctx), ctx, t.config, name)
+	endSpan()
 	if runErr != nil {
 		return runErr
 	}
@@ -52,7 +55,7 @@ func (t *Task) buildImageWithMounts(ctx *context.ExecuteContext, imageName strin
 	if err != nil {
 		return err
 	}
-	return image.Stream(os.Stdout, func(out io.Writer) error {
+	return image.Stream(ctx.Stdout, func(out io.Writer) error {
 		opts := buildImageOptions(ctx, out)
 		opts.InputStream = buildContext
 		opts.Name = imageName
@@ -112,11 +115,15 @@ func buildImageOptions(ctx *context.ExecuteContext, out io.Writer) docker.BuildI
 		RawJSONStream:  true,
 		SuppressOutput: ctx.Settings.Quiet,
 		AuthConfigs:    ctx.GetAuthConfigs(),
+		Context:        ctx.Context,
 	}
 }
 
-func removeImage(logger *log.Entry, client client.DockerClient, imageID string) {
-	if err := client.RemoveImage(imageID); err != nil {
+// removeImage removes the image built for the job. It is called after the job
+// has run, so the remove is only stopped by the Kill Context.
+func removeImage(ctx *context.ExecuteContext, logger *log.Entry, imageID string) {
+	options := docker.RemoveImageOptions{Context: ctx.Kill}
+	if err := ctx.Client.RemoveImageExtended(imageID, options); err != nil {
 		logger.Warnf("failed to remove %q: %s", imageID, err)
 	}
 }
@@ -141,11 +148,12 @@ func copyFilesToHost(
 		opts := docker.DownloadFromContainerOptions{
 			Path:         artifactPath.containerDir(),
 			OutputStream: buf,
+			Context:      ctx.Context,
 		}
 		if err := ctx.Client.DownloadFromContainer(containerID, opts); err != nil {
 			return err
 		}
-		if err := unpack(buf, artifactPath); err != nil {
+		if err := unpack(logger, buf, artifactPath); err != nil {
 			return err
 		}
 	}
@@ -257,7 +265,7 @@ func rebasePath(path, oldPrefix, newPrefix string) string {
 	return filepathJoinPreserveDirectorySlash(newPrefix, relativePath)
 }
 
-func unpack(source io.Reader, path artifactPath) error {
+func unpack(logger log.FieldLogger, source io.Reader, path artifactPath) error {
 	tarReader := tar.NewReader(source)
 
 	for {
@@ -278,7 +286,7 @@ func unpack(source io.Reader, path artifactPath) error {
 			continue
 		}
 
-		if err := createFromTar(tarReader, header, path); err != nil {
+		if err := createFromTar(logger, tarReader, header, path); err != nil {
 			return err
 		}
 	}
@@ -298,17 +306,22 @@ func endsWithSlash(path string) bool {
 }
 
 // create files and directories from tar archive entries
-func createFromTar(tarReader io.Reader, header *tar.Header, path artifactPath) error {
+func createFromTar(
+	logger log.FieldLogger,
+	tarReader io.Reader,
+	header *tar.Header,
+	path artifactPath,
+) error {
 	hostPath := path.hostPath(path.pathFromArchive(header.Name))
 	fileMode := header.FileInfo().Mode()
 
 	switch header.Typeflag {
 	case tar.TypeDir:
-		logging.Log.Debugf("Creating dir %s", hostPath)
+		logger.Debugf("Creating dir %s", hostPath)
 		return os.MkdirAll(hostPath, fileMode)
 
 	case tar.TypeReg, tar.TypeRegA:
-		logging.Log.Debugf("Creating file %s", hostPath)
+		logger.Debugf("Creating file %s", hostPath)
 		if err := os.MkdirAll(filepath.Dir(hostPath), 0755); err != nil {
 			return err
 		}
@@ -320,7 +333,7 @@ func createFromTar(tarReader io.Reader, header *tar.Header, path artifactPath) e
 		return err
 
 	case tar.TypeSymlink:
-		logging.Log.Debugf("Creating symlink %s", hostPath)
+		logger.Debugf("Creating symlink %s", hostPath)
 		if err := os.MkdirAll(filepath.Dir(hostPath), 0755); err != nil {
 			return err
 		}
@@ -328,7 +341,7 @@ func createFromTar(tarReader io.Reader, header *tar.Header, path artifactPath) e
 		return os.Symlink(header.Linkname, hostPath)
 
 	default:
-		logging.Log.Warnf("Unhandled file type from archive %s: %s",
+		logger.Warnf("Unhandled file type from archive %s: %s",
 			string(header.Typeflag),
 			header.Name)
 	}
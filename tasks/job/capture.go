@@ -45,6 +45,18 @@ func (t *captureTask) Repr() string {
 	return fmt.Sprintf("%s capture %s", t.runTask.name.Format("job"), t.variable)
 }
 
+// IsStale returns the Staleness of the job
+func (t *captureTask) IsStale(ctx *context.ExecuteContext) (types.Staleness, error) {
+	return t.runTask.IsStale(ctx)
+}
+
+// Plan marks the variable as unknown, because the value is the output of the
+// job
+func (t *captureTask) Plan(ctx *context.ExecuteContext) error {
+	ctx.Env.SetUnknownVariable(t.variable, t.runTask.name.Name())
+	return nil
+}
+
 // Run the job to capture the output in a variable
 func (t *captureTask) Run(ctx *context.ExecuteContext, depsModified bool) (bool, error) {
 	modified, err := t.runTask.Run(ctx, depsModified)
@@ -57,6 +69,6 @@ func (t *captureTask) Run(ctx *context.ExecuteContext, depsModified bool) (bool,
 		return false, nil
 	}
 
-	logging.ForTask(t).Debugf("Setting %q to: %s", t.variable, out)
+	logging.ForTask(ctx.Logger, t).Debugf("Setting %q to: %s", t.variable, out)
 	return true, os.Setenv(t.variable, out)
 }
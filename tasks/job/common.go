@@ -1,7 +1,11 @@
 package job
 
 import (
+	gocontext "context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"regexp"
 
 	"github.com/dnephin/dobi/tasks/client"
 	"github.com/dnephin/dobi/tasks/context"
@@ -9,14 +13,26 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-// containerName returns the name of the container
+// invalidContainerChars matches characters which are not allowed in a container
+// name, like the brackets in the name of a job expanded from a matrix
+var invalidContainerChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+// containerName returns the name of the container. Invalid characters in the
+// name are replaced, and a short hash of the original name is added so that
+// names which only differ by the replaced characters do not collide.
 func containerName(ctx *context.ExecuteContext, name string) string {
+	if invalidContainerChars.MatchString(name) {
+		sum := sha256.Sum256([]byte(name))
+		name = invalidContainerChars.ReplaceAllString(name, "_") + "-" +
+			hex.EncodeToString(sum[:])[:8]
+	}
 	return fmt.Sprintf("%s-%s", ctx.Env.Unique(), name)
 }
 
 // removeContainer removes a container by ID, and logs a warning if the remove
 // fails.
 func removeContainer(
+	gctx gocontext.Context,
 	logger *log.Entry,
 	client client.DockerClient,
 	containerID string,
@@ -26,6 +42,7 @@ func removeContainer(
 		ID:            containerID,
 		RemoveVolumes: true,
 		Force:         true,
+		Context:       gctx,
 	})
 	switch err.(type) {
 	case *docker.NoSuchContainer:
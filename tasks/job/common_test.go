@@ -0,0 +1,22 @@
+package job
+
+import (
+	"testing"
+
+	"github.com/dnephin/dobi/execenv"
+	"github.com/dnephin/dobi/tasks/context"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestContainerNameReplacesInvalidCharacters(t *testing.T) {
+	ctx := &context.ExecuteContext{Env: execenv.NewExecEnv("1234", "proj", "/work")}
+	name := containerName(ctx, "test[arch=amd64,go=1.21]")
+	assert.Check(t, is.Equal("proj-1234-test_arch_amd64_go_1.21_-46e5fd11", name))
+}
+
+func TestContainerNameDoesNotCollideAfterReplacingCharacters(t *testing.T) {
+	ctx := &context.ExecuteContext{Env: execenv.NewExecEnv("1234", "proj", "/work")}
+	assert.Check(t, containerName(ctx, "t[a=b]") != containerName(ctx, "t_a_b_"))
+	assert.Check(t, is.Equal("proj-1234-t_a_b_", containerName(ctx, "t_a_b_")))
+}
@@ -0,0 +1,115 @@
+package job
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/image"
+	"github.com/dnephin/dobi/tasks/types"
+	"github.com/dnephin/dobi/utils/fs"
+	"github.com/pkg/errors"
+)
+
+const jobDigestDir = ".dobi/jobs"
+
+// isStaleByDigest compares the digest of the sources to the digest from the
+// last time the job ran
+func (t *Task) isStaleByDigest(ctx *context.ExecuteContext) (types.Staleness, error) {
+	digest, err := t.sourcesDigest(ctx)
+	if err != nil {
+		t.logger(ctx).Warnf("Failed to get digest of sources: %s", err)
+		return types.Stale("failed to get digest of sources"), err
+	}
+	t.digest = digest
+
+	previous, err := ioutil.ReadFile(digestPath(ctx, t.name.Resource()))
+	details := []string{"sources digest: " + shortDigest(digest)}
+	switch {
+	case os.IsNotExist(err):
+		return types.Stale("no digest from a previous run").Explain(details...), nil
+	case err != nil:
+		t.logger(ctx).Warnf("Failed to read digest: %s", err)
+		return types.Stale("failed to read digest"), nil
+	}
+	details = append(details, "digest from last run: "+shortDigest(string(previous)))
+	if string(previous) != digest {
+		return types.Stale("sources digest changed").Explain(details...), nil
+	}
+	return types.Fresh("sources digest unchanged").Explain(details...), nil
+}
+
+// shortDigest returns the first line of a digest, shortened for display
+func shortDigest(digest string) string {
+	digest = strings.TrimPrefix(strings.SplitN(digest, "\n", 2)[0], "sha256:")
+	if len(digest) > 12 {
+		return digest[:12]
+	}
+	return digest
+}
+
+// sourcesDigest returns the digest of the sources when the job uses hash
+// freshness, otherwise it returns an empty string. When the job has no sources
+// the digest is made from the mount files and the ID of the image.
+func (t *Task) sourcesDigest(ctx *context.ExecuteContext) (string, error) {
+	if t.config.Freshness != config.FreshnessHash {
+		return "", nil
+	}
+	if len(t.config.Sources.Paths()) != 0 {
+		return fs.Digest(&fs.LastModifiedSearch{
+			Root:  ctx.WorkingDir,
+			Paths: t.config.Sources.Paths(),
+		})
+	}
+
+	mountPaths := []string{}
+	ctx.Resources.EachMount(t.config.Mounts, func(name string, mount *config.MountConfig) {
+		mountPaths = append(mountPaths, mount.Bind)
+	})
+	mountsDigest, err := fs.Digest(&fs.LastModifiedSearch{
+		Root:  ctx.WorkingDir,
+		Paths: mountPaths,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	imageName := ctx.Resources.Image(t.config.Use)
+	taskImage, err := image.GetImage(ctx, imageName)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get image %q", imageName)
+	}
+	return strings.Join([]string{mountsDigest, taskImage.ID}, "\n"), nil
+}
+
+// runDigest returns the digest of the sources for this run of the job. The
+// digest from the staleness check is used when there was one, so the sources
+// are only read once.
+func (t *Task) runDigest(ctx *context.ExecuteContext) (string, error) {
+	if t.digest != "" {
+		return t.digest, nil
+	}
+	return t.sourcesDigest(ctx)
+}
+
+// updateDigest stores the digest of the sources from this run of the job
+func (t *Task) updateDigest(ctx *context.ExecuteContext, digest string) {
+	if digest == "" {
+		return
+	}
+	path := digestPath(ctx, t.name.Resource())
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err == nil {
+		err = ioutil.WriteFile(path, []byte(digest), 0644)
+	}
+	if err != nil {
+		t.logger(ctx).Warnf("Failed to update digest: %s", err)
+	}
+}
+
+func digestPath(ctx *context.ExecuteContext, resource string) string {
+	return filepath.Join(ctx.WorkingDir, jobDigestDir, resource)
+}
@@ -0,0 +1,87 @@
+package job
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	"github.com/dnephin/dobi/tasks/types"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+var ignoreDetails = cmpopts.IgnoreFields(types.Staleness{}, "Details")
+
+func TestIsStaleWithHashFreshness(t *testing.T) {
+	dir := fs.NewDir(t, "test-is-stale-with-hash-freshness",
+		fs.WithFile("main.go", "package main"),
+		fs.WithFile("app", "binary"))
+	defer dir.Remove()
+
+	conf := &config.JobConfig{Freshness: config.FreshnessHash}
+	assert.NilError(t, conf.Sources.TransformConfig(reflect.ValueOf(dir.Join("main.go"))))
+	assert.NilError(t, conf.Artifact.TransformConfig(reflect.ValueOf(dir.Join("app"))))
+	job := &Task{name: task.NewDefaultName("compile", "run"), config: conf}
+	ctx := &context.ExecuteContext{WorkingDir: dir.Path(), Logger: logging.Log}
+
+	staleness, err := job.IsStale(ctx)
+	assert.NilError(t, err)
+	expected := types.Stale("no digest from a previous run")
+	assert.Check(t, is.DeepEqual(expected, staleness, ignoreDetails))
+
+	digest, err := job.sourcesDigest(ctx)
+	assert.NilError(t, err)
+	job.updateDigest(ctx, digest)
+
+	staleness, err = job.IsStale(ctx)
+	assert.NilError(t, err)
+	expected = types.Fresh("sources digest unchanged")
+	assert.Check(t, is.DeepEqual(expected, staleness, ignoreDetails))
+
+	content := []byte("package other")
+	assert.NilError(t, ioutil.WriteFile(dir.Join("main.go"), content, 0644))
+	staleness, err = job.IsStale(ctx)
+	assert.NilError(t, err)
+	expected = types.Stale("sources digest changed")
+	assert.Check(t, is.DeepEqual(expected, staleness, ignoreDetails))
+	assert.Assert(t, is.Len(staleness.Details, 3))
+	assert.Check(t, is.Contains(staleness.Details[0], "artifact: app modified"))
+	assert.Check(t, is.Equal("digest from last run: "+shortDigest(digest), staleness.Details[2]))
+}
+
+func TestRunDigestUsesDigestFromStalenessCheck(t *testing.T) {
+	dir := fs.NewDir(t, "test-run-digest",
+		fs.WithFile("main.go", "package main"),
+		fs.WithFile("app", "binary"))
+	defer dir.Remove()
+
+	conf := &config.JobConfig{Freshness: config.FreshnessHash}
+	assert.NilError(t, conf.Sources.TransformConfig(reflect.ValueOf(dir.Join("main.go"))))
+	assert.NilError(t, conf.Artifact.TransformConfig(reflect.ValueOf(dir.Join("app"))))
+	job := &Task{name: task.NewDefaultName("compile", "run"), config: conf}
+	ctx := &context.ExecuteContext{WorkingDir: dir.Path(), Logger: logging.Log}
+
+	_, err := job.IsStale(ctx)
+	assert.NilError(t, err)
+	checked, err := job.sourcesDigest(ctx)
+	assert.NilError(t, err)
+
+	// the sources are not read again, so the run records the digest of the
+	// sources which were checked
+	content := []byte("package other")
+	assert.NilError(t, ioutil.WriteFile(dir.Join("main.go"), content, 0644))
+	digest, err := job.runDigest(ctx)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(checked, digest))
+
+	job = &Task{name: task.NewDefaultName("compile", "run"), config: conf}
+	digest, err = job.runDigest(ctx)
+	assert.NilError(t, err)
+	assert.Check(t, checked != digest)
+}
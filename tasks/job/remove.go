@@ -35,9 +35,10 @@ func (t *RemoveTask) Repr() string {
 
 // Run creates the host path if it doesn't already exist
 func (t *RemoveTask) Run(ctx *context.ExecuteContext, _ bool) (bool, error) {
-	logger := logging.ForTask(t)
+	logger := logging.ForTask(ctx.Logger, t)
 
-	removeContainer(logger, ctx.Client, containerName(ctx, t.name.Resource())) // nolint: errcheck
+	name := containerName(ctx, t.name.Resource())
+	removeContainer(ctx.Context, logger, ctx.Client, name) // nolint: errcheck
 
 	for _, path := range t.config.Artifact.Paths() {
 		if err := os.RemoveAll(path); err != nil {
@@ -2,6 +2,7 @@ package job
 
 import (
 	"bytes"
+	gocontext "context"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -17,6 +18,7 @@ import (
 	"github.com/dnephin/dobi/tasks/context"
 	"github.com/dnephin/dobi/tasks/image"
 	"github.com/dnephin/dobi/tasks/mount"
+	"github.com/dnephin/dobi/tasks/retry"
 	"github.com/dnephin/dobi/tasks/task"
 	"github.com/dnephin/dobi/tasks/types"
 	"github.com/dnephin/dobi/utils/fs"
@@ -29,6 +31,10 @@ import (
 // DefaultUnixSocket to connect to the docker API
 const DefaultUnixSocket = "/var/run/docker.sock"
 
+// stopTimeout is the number of seconds a container is given to exit after it
+// is sent its stop signal, before it is killed
+const stopTimeout = 10
+
 func newRunTask(name task.Name, conf config.Resource) types.Task {
 	return &Task{name: name, config: conf.(*config.JobConfig)}
 }
@@ -40,6 +46,8 @@ type Task struct {
 	name      task.Name
 	config    *config.JobConfig
 	outStream io.Writer
+	// digest is the digest of the sources from the staleness check
+	digest string
 }
 
 // Name returns the name of the task
@@ -47,8 +55,8 @@ func (t *Task) Name() task.Name {
 	return t.name
 }
 
-func (t *Task) logger() *log.Entry {
-	return logging.ForTask(t)
+func (t *Task) logger(ctx *context.ExecuteContext) *log.Entry {
+	return logging.ForTask(ctx.Logger, t)
 }
 
 // Repr formats the task for logging
@@ -69,103 +77,132 @@ func (t *Task) Repr() string {
 
 // Run the job command in a container
 func (t *Task) Run(ctx *context.ExecuteContext, depsModified bool) (bool, error) {
-	if !depsModified {
-		stale, err := t.isStale(ctx)
-		switch {
-		case err != nil:
-			return false, err
-		case !stale:
-			t.logger().Info("is fresh")
-			return false, nil
-		}
+	staleness, err := types.CheckStaleness(ctx, t.name, depsModified,
+		func() (types.Staleness, error) { return t.IsStale(ctx) })
+	switch {
+	case err != nil:
+		return false, err
+	case !staleness.Stale:
+		t.logger(ctx).Info("is fresh")
+		return false, nil
 	}
-	t.logger().Debug("is stale")
+	t.logger(ctx).Debug(staleness.Reason)
+	t.logger(ctx).Debug("is stale")
 
-	t.logger().Info("Start")
-	var err error
-	if ctx.Settings.BindMount {
-		err = t.runContainerWithBinds(ctx)
-	} else {
-		err = t.runWithBuildAndCopy(ctx)
-	}
+	t.logger(ctx).Info("Start")
+	ctx, cancel := ctx.WithTimeout(t.config.Timeout.Value())
+	defer cancel()
+
+	digest, err := t.runDigest(ctx)
 	if err != nil {
 		return false, err
 	}
-	t.logger().Info("Done")
+	err = retry.Do(ctx.Context, t.logger(ctx), t.config.Retry, t.retryable, func() error {
+		if ctx.Settings.BindMount {
+			return t.runContainerWithBinds(ctx)
+		}
+		return t.runWithBuildAndCopy(ctx)
+	})
+	if err != nil {
+		return false, ctx.TimeoutError(err)
+	}
+	t.updateDigest(ctx, digest)
+	t.logger(ctx).Info("Done")
 	return true, nil
 }
 
+// IsStale returns a stale Staleness if the artifact is older than the sources,
+// or the mounts and the image when there are no sources. With hash freshness
+// the job is stale when the digest of the sources has changed. The details of
+// the Staleness include the newest file of the artifact, sources, and mounts.
 // nolint: gocyclo
-func (t *Task) isStale(ctx *context.ExecuteContext) (bool, error) {
+func (t *Task) IsStale(ctx *context.ExecuteContext) (types.Staleness, error) {
+	if len(ctx.Settings.Args[t.name.Resource()]) > 0 {
+		return types.Stale("arguments were given on the command line"), nil
+	}
 	if t.config.Artifact.Empty() {
-		return true, nil
+		return types.Stale("job has no artifact"), nil
 	}
 
-	artifactLastModified, err := t.artifactLastModified(ctx.WorkingDir)
+	artifact, artifactLastModified, err := t.newestArtifact(ctx.WorkingDir)
 	if err != nil {
-		t.logger().Warnf("Failed to get artifact last modified: %s", err)
-		return true, err
+		t.logger(ctx).Warnf("Failed to get artifact last modified: %s", err)
+		return types.Stale("failed to get artifact last modified"), err
 	}
+	details := []string{types.FileDetail("artifact", artifact, artifactLastModified)}
 
 	if t.config.Sources.NoMatches() {
-		t.logger().Warnf("No sources found matching: %s", &t.config.Sources)
-		return true, nil
+		t.logger(ctx).Warnf("No sources found matching: %s", &t.config.Sources)
+		return types.Stale("no sources found").Explain(
+			fmt.Sprintf("sources: no files match %s", &t.config.Sources)), nil
+	}
+
+	if t.config.Freshness == config.FreshnessHash {
+		if artifactLastModified.IsZero() {
+			return types.Stale("artifact does not exist").Explain(details...), nil
+		}
+		staleness, err := t.isStaleByDigest(ctx)
+		staleness.Details = append(details, staleness.Details...)
+		return staleness, err
 	}
 
 	if len(t.config.Sources.Paths()) != 0 {
-		sourcesLastModified, err := fs.LastModified(&fs.LastModifiedSearch{
+		source, sourcesLastModified, err := fs.NewestFile(&fs.LastModifiedSearch{
 			Root:  ctx.WorkingDir,
 			Paths: t.config.Sources.Paths(),
 		})
 		if err != nil {
-			return true, err
+			return types.Stale("failed to get sources last modified"), err
 		}
+		details = append(details, types.FileDetail("newest source", source, sourcesLastModified))
 		if artifactLastModified.Before(sourcesLastModified) {
-			t.logger().Debug("artifact older than sources")
-			return true, nil
+			return types.Stale("artifact older than sources").Explain(details...), nil
 		}
-		return false, nil
+		return types.Fresh("artifact newer than sources").Explain(details...), nil
 	}
 
-	mountsLastModified, err := t.mountsLastModified(ctx)
+	mountFile, mountsLastModified, err := t.newestMountFile(ctx)
 	if err != nil {
-		t.logger().Warnf("Failed to get mounts last modified: %s", err)
-		return true, err
+		t.logger(ctx).Warnf("Failed to get mounts last modified: %s", err)
+		return types.Stale("failed to get mounts last modified"), err
 	}
+	details = append(details,
+		types.FileDetail("newest mount file", mountFile, mountsLastModified))
 
 	if artifactLastModified.Before(mountsLastModified) {
-		t.logger().Debug("artifact older than mount files")
-		return true, nil
+		return types.Stale("artifact older than mount files").Explain(details...), nil
 	}
 
 	imageName := ctx.Resources.Image(t.config.Use)
 	taskImage, err := image.GetImage(ctx, imageName)
 	if err != nil {
-		return true, fmt.Errorf("failed to get image %q: %s", imageName, err)
+		return types.Stale("failed to get image"),
+			fmt.Errorf("failed to get image %q: %s", imageName, err)
 	}
+	details = append(details, fmt.Sprintf("image: %s created %s",
+		image.GetImageName(ctx, imageName), types.FormatTime(taskImage.Created)))
 	if artifactLastModified.Before(taskImage.Created) {
-		t.logger().Debug("artifact older than image")
-		return true, nil
+		return types.Stale("artifact older than image").Explain(details...), nil
 	}
-	return false, nil
+	return types.Fresh("artifact newer than mount files and image").Explain(details...), nil
 }
 
-func (t *Task) artifactLastModified(workDir string) (time.Time, error) {
+func (t *Task) newestArtifact(workDir string) (string, time.Time, error) {
 	paths := t.config.Artifact.Paths()
 	// File or directory doesn't exist
 	if len(paths) == 0 {
-		return time.Time{}, nil
+		return "", time.Time{}, nil
 	}
-	return fs.LastModified(&fs.LastModifiedSearch{Root: workDir, Paths: paths})
+	return fs.NewestFile(&fs.LastModifiedSearch{Root: workDir, Paths: paths})
 }
 
 // TODO: support a .mountignore file used to ignore mtime of files
-func (t *Task) mountsLastModified(ctx *context.ExecuteContext) (time.Time, error) {
+func (t *Task) newestMountFile(ctx *context.ExecuteContext) (string, time.Time, error) {
 	mountPaths := []string{}
 	ctx.Resources.EachMount(t.config.Mounts, func(name string, mount *config.MountConfig) {
 		mountPaths = append(mountPaths, mount.Bind)
 	})
-	return fs.LastModified(&fs.LastModifiedSearch{Root: ctx.WorkingDir, Paths: mountPaths})
+	return fs.NewestFile(&fs.LastModifiedSearch{Root: ctx.WorkingDir, Paths: mountPaths})
 }
 
 func (t *Task) runContainerWithBinds(ctx *context.ExecuteContext) error {
@@ -173,16 +210,19 @@ func (t *Task) runContainerWithBinds(ctx *context.ExecuteContext) error {
 	imageName := image.GetImageName(ctx, ctx.Resources.Image(t.config.Use))
 	options := t.createOptions(ctx, name, imageName)
 
-	defer removeContainerWithLogging(t.logger(), ctx.Client, name)
+	defer removeContainerWithLogging(ctx.Kill, t.logger(ctx), ctx.Client, name)
 	return t.runContainer(ctx, options)
 }
 
+// removeContainerWithLogging removes the container after the job has run, so
+// the remove is only stopped when gctx is the Kill Context and it is cancelled.
 func removeContainerWithLogging(
+	gctx gocontext.Context,
 	logger *log.Entry,
 	client client.DockerClient,
 	containerID string,
 ) {
-	removed, err := removeContainer(logger, client, containerID)
+	removed, err := removeContainer(gctx, logger, client, containerID)
 	if !removed && err == nil {
 		logger.WithFields(log.Fields{"container": containerID}).Warn(
 			"Container does not exist")
@@ -194,22 +234,25 @@ func (t *Task) runContainer(
 	options docker.CreateContainerOptions,
 ) error {
 	name := options.Name
+	options.Context = ctx.Context
+	endSpan := ctx.Span(t.name, "create container")
 	container, err := ctx.Client.CreateContainer(options)
+	endSpan()
 	if err != nil {
 		return fmt.Errorf("failed creating container %q: %s", name, err)
 	}
 
-	chanSig := t.forwardSignals(ctx.Client, container.ID)
+	chanSig := t.forwardSignals(ctx, container.ID)
 	defer signal.Stop(chanSig)
 
 	closeWaiter, err := ctx.Client.AttachToContainerNonBlocking(docker.AttachToContainerOptions{
 		Container:    container.ID,
-		OutputStream: t.output(),
-		ErrorStream:  os.Stderr,
+		OutputStream: t.output(ctx),
+		ErrorStream:  ctx.Stderr,
 		InputStream:  ioutil.NopCloser(os.Stdin),
 		Stream:       true,
-		Stdin:        t.config.Interactive,
-		RawTerminal:  t.config.Interactive,
+		Stdin:        t.interactive(ctx),
+		RawTerminal:  t.interactive(ctx),
 		Stdout:       true,
 		Stderr:       true,
 	})
@@ -218,7 +261,7 @@ func (t *Task) runContainer(
 	}
 	defer closeWaiter.Wait() // nolint: errcheck
 
-	if t.config.Interactive {
+	if t.interactive(ctx) {
 		inFd, _ := term.GetFdInfo(os.Stdin)
 		state, err := term.SetRawTerminal(inFd)
 		if err != nil {
@@ -226,24 +269,89 @@ func (t *Task) runContainer(
 		}
 		defer func() {
 			if err := term.RestoreTerminal(inFd, state); err != nil {
-				t.logger().Warnf("Failed to restore fd %v: %s", inFd, err)
+				t.logger(ctx).Warnf("Failed to restore fd %v: %s", inFd, err)
 			}
 		}()
 	}
 
-	if err := ctx.Client.StartContainer(container.ID, nil); err != nil {
+	endSpan = ctx.Span(t.name, "start container")
+	err = ctx.Client.StartContainerWithContext(container.ID, nil, ctx.Context)
+	endSpan()
+	if err != nil {
 		return fmt.Errorf("failed starting container %q: %s", name, err)
 	}
 
 	initWindow(chanSig)
-	return t.wait(ctx.Client, container.ID)
+	stopWaiting := t.stopOnCancel(ctx, container.ID)
+	defer close(stopWaiting)
+	defer ctx.Span(t.name, "wait for container")()
+	return t.wait(ctx, container.ID)
+}
+
+// stopOnCancel stops the container when the Context is cancelled, which
+// happens when dobi is interrupted, or when dobi watch sees a change. The
+// container is sent its stop signal, and is killed if it has not exited after
+// stopTimeout seconds. The container is killed immediately when the job times
+// out, or when the Kill Context is cancelled. Closing the returned channel
+// stops waiting for the Contexts.
+func (t *Task) stopOnCancel(ctx *context.ExecuteContext, containerID string) chan<- struct{} {
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Context.Done():
+		case <-stop:
+			return
+		}
+		select {
+		case <-stop:
+			// the container exited before the Context was cancelled
+			return
+		default:
+		}
+		if ctx.Context.Err() == gocontext.DeadlineExceeded {
+			t.killContainer(ctx, containerID, ctx.Context.Err())
+			return
+		}
+
+		t.logger(ctx).Warnf("Stopping container: %s", ctx.Context.Err())
+		go func() {
+			if err := ctx.Client.StopContainer(containerID, stopTimeout); err != nil {
+				t.logger(ctx).WithError(err).Warn("Failed to stop container")
+			}
+		}()
+		select {
+		case <-ctx.Kill.Done():
+			t.killContainer(ctx, containerID, ctx.Kill.Err())
+		case <-stop:
+		}
+	}()
+	return stop
+}
+
+func (t *Task) killContainer(ctx *context.ExecuteContext, containerID string, reason error) {
+	logger := t.logger(ctx).WithField("signal", syscall.SIGKILL)
+	logger.Warnf("Killing container: %s", reason)
+	handleShutdownSignals(logger, ctx.Client, containerID, syscall.SIGKILL)
+}
+
+// interactive returns true if the container should be attached to the
+// terminal. Tasks running in parallel share the terminal, so they are never
+// interactive.
+func (t *Task) interactive(ctx *context.ExecuteContext) bool {
+	return t.config.Interactive && ctx.Settings.Parallel <= 1
 }
 
-func (t *Task) output() io.Writer {
+func (t *Task) output(ctx *context.ExecuteContext) io.Writer {
 	if t.outStream == nil {
-		return os.Stdout
+		return ctx.Stdout
 	}
-	return io.MultiWriter(t.outStream, os.Stdout)
+	return io.MultiWriter(t.outStream, ctx.Stdout)
+}
+
+// command returns the command for the container, with any arguments from the
+// command line
+func (t *Task) command(ctx *context.ExecuteContext) []string {
+	return t.config.Command.WithArgs(ctx.Settings.Args[t.name.Resource()])
 }
 
 func (t *Task) createOptions(
@@ -251,15 +359,15 @@ func (t *Task) createOptions(
 	name string,
 	imageName string,
 ) docker.CreateContainerOptions {
-	t.logger().Debugf("Image name %q", imageName)
+	t.logger(ctx).Debugf("Image name %q", imageName)
 
-	interactive := t.config.Interactive
+	interactive := t.interactive(ctx)
 	portBinds, exposedPorts := asPortBindings(t.config.Ports)
 	// TODO: only set Tty if running in a tty
 	opts := docker.CreateContainerOptions{
 		Name: name,
 		Config: &docker.Config{
-			Cmd:          t.config.Command.Value(),
+			Cmd:          t.command(ctx),
 			Image:        imageName,
 			User:         t.config.User,
 			OpenStdin:    interactive,
@@ -344,28 +452,50 @@ func provideDocker(opts docker.CreateContainerOptions) docker.CreateContainerOpt
 	return opts
 }
 
-func (t *Task) wait(client client.DockerClient, containerID string) error {
-	status, err := client.WaitContainer(containerID)
+// ExitCodeError is returned when the container of a job exits with a non-zero
+// exit code
+type ExitCodeError struct {
+	Code int
+}
+
+func (e *ExitCodeError) Error() string {
+	return fmt.Sprintf("exited with non-zero status code %d", e.Code)
+}
+
+// wait for the container to exit. The wait is not cancelled with the Context,
+// so that the exit status is returned after the container is stopped.
+func (t *Task) wait(ctx *context.ExecuteContext, containerID string) error {
+	status, err := ctx.Client.WaitContainerWithContext(containerID, gocontext.Background())
 	if err != nil {
 		return fmt.Errorf("failed to wait on container exit: %s", err)
 	}
 	if status != 0 {
-		return fmt.Errorf("exited with non-zero status code %d", status)
+		return &ExitCodeError{Code: status}
 	}
 	return nil
 }
 
+// retryable returns true if the job failed with an exit code that is retried
+// by the retry policy of the job
+func (t *Task) retryable(err error) bool {
+	exitErr, ok := err.(*ExitCodeError)
+	return ok && t.config.Retry.RetriesExitCode(exitErr.Code)
+}
+
+// forwardSignals resizes the TTY of the container when the window changes.
+// SIGINT and SIGTERM are not forwarded, they cancel the Context of the run,
+// which stops the container.
 func (t *Task) forwardSignals(
-	client client.DockerClient,
+	ctx *context.ExecuteContext,
 	containerID string,
 ) chan<- os.Signal {
 	chanSig := make(chan os.Signal, 128)
 
-	signal.Notify(chanSig, syscall.SIGINT, syscall.SIGTERM, SIGWINCH)
+	signal.Notify(chanSig, SIGWINCH)
 
 	go func() {
 		for sig := range chanSig {
-			logger := t.logger().WithField("signal", sig)
+			logger := t.logger(ctx).WithField("signal", sig)
 			logger.Debug("received")
 
 			sysSignal, ok := sig.(syscall.Signal)
@@ -376,9 +506,9 @@ func (t *Task) forwardSignals(
 
 			switch sysSignal {
 			case SIGWINCH:
-				handleWinSizeChangeSignal(logger, client, containerID)
+				handleWinSizeChangeSignal(logger, ctx.Client, containerID)
 			default:
-				handleShutdownSignals(logger, client, containerID, sysSignal)
+				handleShutdownSignals(logger, ctx.Client, containerID, sysSignal)
 			}
 		}
 	}()
@@ -0,0 +1,112 @@
+package job
+
+import (
+	gocontext "context"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks/client"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/mock/gomock"
+)
+
+func setupStopOnCancel(
+	t *testing.T,
+	gctx gocontext.Context,
+	kill gocontext.Context,
+) (*client.MockDockerClient, func() chan<- struct{}, func()) {
+	mock := gomock.NewController(t)
+	mockClient := client.NewMockDockerClient(mock)
+	ctx := &context.ExecuteContext{
+		Client:  mockClient,
+		Context: gctx,
+		Kill:    kill,
+		Logger:  logging.Log,
+	}
+	job := &Task{name: task.NewDefaultName("test", "run"), config: &config.JobConfig{}}
+	start := func() chan<- struct{} {
+		return job.stopOnCancel(ctx, "container-id")
+	}
+	return mockClient, start, mock.Finish
+}
+
+func waitFor(t *testing.T, called <-chan struct{}, name string) {
+	t.Helper()
+	select {
+	case <-called:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timeout waiting for %s", name)
+	}
+}
+
+func TestStopOnCancelStopsContainerWhenCancelled(t *testing.T) {
+	gctx, cancel := gocontext.WithCancel(gocontext.Background())
+	mockClient, start, teardown := setupStopOnCancel(t, gctx, gocontext.Background())
+	defer teardown()
+
+	stopped := make(chan struct{})
+	mockClient.EXPECT().StopContainer("container-id", uint(stopTimeout)).
+		Do(func(string, uint) { close(stopped) }).
+		Return(nil)
+
+	stop := start()
+	cancel()
+	waitFor(t, stopped, "StopContainer")
+	close(stop)
+}
+
+func TestStopOnCancelKillsContainerOnTimeout(t *testing.T) {
+	gctx, cancel := gocontext.WithTimeout(gocontext.Background(), time.Millisecond)
+	defer cancel()
+	mockClient, start, teardown := setupStopOnCancel(t, gctx, gocontext.Background())
+	defer teardown()
+
+	killed := make(chan struct{})
+	mockClient.EXPECT().KillContainer(docker.KillContainerOptions{
+		ID:     "container-id",
+		Signal: docker.Signal(syscall.SIGKILL),
+	}).Do(func(docker.KillContainerOptions) { close(killed) }).Return(nil)
+
+	stop := start()
+	waitFor(t, killed, "KillContainer")
+	close(stop)
+}
+
+func TestStopOnCancelKillsContainerWhenKilled(t *testing.T) {
+	gctx, cancel := gocontext.WithCancel(gocontext.Background())
+	kill, cancelKill := gocontext.WithCancel(gocontext.Background())
+	mockClient, start, teardown := setupStopOnCancel(t, gctx, kill)
+	defer teardown()
+
+	stopped := make(chan struct{})
+	killed := make(chan struct{})
+	mockClient.EXPECT().StopContainer("container-id", uint(stopTimeout)).
+		Do(func(string, uint) { close(stopped) }).
+		Return(nil)
+	mockClient.EXPECT().KillContainer(docker.KillContainerOptions{
+		ID:     "container-id",
+		Signal: docker.Signal(syscall.SIGKILL),
+	}).Do(func(docker.KillContainerOptions) { close(killed) }).Return(nil)
+
+	stop := start()
+	cancel()
+	waitFor(t, stopped, "StopContainer")
+	cancelKill()
+	waitFor(t, killed, "KillContainer")
+	close(stop)
+}
+
+func TestStopOnCancelDoesNothingWhenContainerExits(t *testing.T) {
+	gctx, cancel := gocontext.WithCancel(gocontext.Background())
+	_, start, teardown := setupStopOnCancel(t, gctx, gocontext.Background())
+	defer teardown()
+
+	stop := start()
+	close(stop)
+	cancel()
+}
@@ -17,9 +17,10 @@ func GetTaskConfig(name, action string, conf *config.MountConfig) (types.TaskCon
 	}
 	switch action {
 	case "", "create":
-		return newTaskConfig(task.NewDefaultName(name, action), NewTask(runCreate))
+		return newTaskConfig(
+			task.NewDefaultName(name, "create"), NewTask(runCreate, createIsStale))
 	case "remove", "rm":
-		return newTaskConfig(task.NewName(name, action), NewTask(remove))
+		return newTaskConfig(task.NewName(name, action), NewTask(remove, nil))
 	default:
 		return nil, fmt.Errorf("invalid mount action %q for task %q", action, name)
 	}
@@ -27,8 +28,15 @@ func GetTaskConfig(name, action string, conf *config.MountConfig) (types.TaskCon
 
 // NewTask creates a new Task object
 func NewTask(
-	runFunc func(task *Task, ctx *context.ExecuteContext) (bool, error)) types.TaskBuilder {
+	runFunc func(task *Task, ctx *context.ExecuteContext) (bool, error),
+	isStale func(task *Task, ctx *context.ExecuteContext) types.Staleness,
+) types.TaskBuilder {
 	return func(name task.Name, conf config.Resource) types.Task {
-		return &Task{name: name, config: conf.(*config.MountConfig), run: runFunc}
+		return &Task{
+			name:    name,
+			config:  conf.(*config.MountConfig),
+			run:     runFunc,
+			isStale: isStale,
+		}
 	}
 }
@@ -0,0 +1,19 @@
+package mount
+
+import (
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/task"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestGetTaskConfigNamesDefaultAction(t *testing.T) {
+	for _, action := range []string{"", "create"} {
+		taskConfig, err := GetTaskConfig("source", action, &config.MountConfig{})
+		assert.NilError(t, err)
+		assert.Check(t, is.Equal("source:create", taskConfig.Name().Name()))
+		assert.Check(t, taskConfig.Name().Equal(task.NewDefaultName("source", "")))
+	}
+}
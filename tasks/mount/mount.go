@@ -17,9 +17,10 @@ import (
 // Task is a mount task
 type Task struct {
 	types.NoStop
-	name   task.Name
-	config *config.MountConfig
-	run    func(*Task, *context.ExecuteContext) (bool, error)
+	name    task.Name
+	config  *config.MountConfig
+	run     func(*Task, *context.ExecuteContext) (bool, error)
+	isStale func(*Task, *context.ExecuteContext) types.Staleness
 }
 
 // Name returns the name of the task
@@ -27,8 +28,8 @@ func (t *Task) Name() task.Name {
 	return t.name
 }
 
-func (t *Task) logger() *log.Entry {
-	return logging.ForTask(t)
+func (t *Task) logger(ctx *context.ExecuteContext) *log.Entry {
+	return logging.ForTask(ctx.Logger, t)
 }
 
 // Repr formats the task for logging
@@ -38,9 +39,27 @@ func (t *Task) Repr() string {
 
 // Run performs the task action
 func (t *Task) Run(ctx *context.ExecuteContext, _ bool) (bool, error) {
+	staleness, err := types.CheckStaleness(ctx, t.name, false,
+		func() (types.Staleness, error) { return t.IsStale(ctx) })
+	switch {
+	case err != nil:
+		return false, err
+	case !staleness.Stale:
+		t.logger(ctx).Debug("is fresh")
+		return false, nil
+	}
 	return t.run(t, ctx)
 }
 
+// IsStale checks if the action needs to run. Actions which do not check for
+// changes are always stale.
+func (t *Task) IsStale(ctx *context.ExecuteContext) (types.Staleness, error) {
+	if t.isStale == nil {
+		return types.Stale("always runs"), nil
+	}
+	return t.isStale(t, ctx), nil
+}
+
 type createAction struct {
 	task *Task
 }
@@ -52,7 +71,7 @@ func runCreate(task *Task, ctx *context.ExecuteContext) (bool, error) {
 }
 
 func (t *createAction) run(ctx *context.ExecuteContext) (bool, error) {
-	logger := logging.ForTask(t.task)
+	logger := logging.ForTask(ctx.Logger, t.task)
 
 	if t.exists(ctx) {
 		logger.Debug("is fresh")
@@ -73,6 +92,14 @@ func (t *createAction) run(ctx *context.ExecuteContext) (bool, error) {
 	return true, nil
 }
 
+func createIsStale(task *Task, ctx *context.ExecuteContext) types.Staleness {
+	c := createAction{task: task}
+	if c.exists(ctx) {
+		return types.Fresh("mount exists")
+	}
+	return types.Stale("mount does not exist")
+}
+
 func (t *createAction) createBind(ctx *context.ExecuteContext) error {
 	path := AbsBindPath(t.task.config, ctx.WorkingDir)
 	mode := os.FileMode(t.task.config.Mode)
@@ -87,7 +114,8 @@ func (t *createAction) createBind(ctx *context.ExecuteContext) error {
 
 func (t *createAction) createNamed(ctx *context.ExecuteContext) error {
 	_, err := ctx.Client.CreateVolume(docker.CreateVolumeOptions{
-		Name: t.task.config.Name,
+		Name:    t.task.config.Name,
+		Context: ctx.Context,
 	})
 	return err
 }
@@ -99,12 +127,12 @@ func (t *createAction) exists(ctx *context.ExecuteContext) bool {
 
 func remove(task *Task, ctx *context.ExecuteContext) (bool, error) {
 	if task.config.Name == "" {
-		logging.ForTask(task).Warn("Bind mounts are not removable")
+		logging.ForTask(ctx.Logger, task).Warn("Bind mounts are not removable")
 		return false, nil
 	}
 
 	if err := ctx.Client.RemoveVolume(task.config.Name); err != nil {
-		task.logger().Warnf("failed to remove %q: %s", task.config.Name, err)
+		task.logger(ctx).Warnf("failed to remove %q: %s", task.config.Name, err)
 	}
 
 	return true, nil
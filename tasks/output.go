@@ -0,0 +1,63 @@
+package tasks
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// syncWriter serializes writes to a writer which is shared by tasks running at
+// the same time.
+type syncWriter struct {
+	lock sync.Mutex
+	out  io.Writer
+}
+
+func newSyncWriter(out io.Writer) *syncWriter {
+	return &syncWriter{out: out}
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.out.Write(p)
+}
+
+// prefixWriter writes each line of output with a prefix, so that the output of
+// tasks running at the same time can be told apart. Partial lines are buffered
+// until they are completed, or until Flush is called.
+type prefixWriter struct {
+	out    io.Writer
+	prefix []byte
+	buf    bytes.Buffer
+}
+
+func newPrefixWriter(out io.Writer, prefix string) *prefixWriter {
+	return &prefixWriter{out: out, prefix: []byte("[" + prefix + "] ")}
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		index := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if index < 0 {
+			return len(p), nil
+		}
+		if err := w.writeLine(w.buf.Next(index + 1)); err != nil {
+			return len(p), err
+		}
+	}
+}
+
+func (w *prefixWriter) writeLine(line []byte) error {
+	_, err := w.out.Write(append(append([]byte{}, w.prefix...), line...))
+	return err
+}
+
+// Flush writes any buffered partial line
+func (w *prefixWriter) Flush() {
+	if w.buf.Len() == 0 {
+		return
+	}
+	w.writeLine(append(w.buf.Next(w.buf.Len()), '\n')) // nolint: errcheck
+}
@@ -0,0 +1,24 @@
+package tasks
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	buf := new(bytes.Buffer)
+	writer := newPrefixWriter(buf, "job:run")
+
+	_, err := writer.Write([]byte("first line\nsecond "))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal("[job:run] first line\n", buf.String()))
+
+	_, err = writer.Write([]byte("line\nlast"))
+	assert.NilError(t, err)
+	writer.Flush()
+	expected := "[job:run] first line\n[job:run] second line\n[job:run] last\n"
+	assert.Check(t, is.Equal(expected, buf.String()))
+}
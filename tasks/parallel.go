@@ -0,0 +1,148 @@
+package tasks
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	"github.com/dnephin/dobi/tasks/types"
+)
+
+// taskNode is a task in the dependency graph of a TaskCollection
+type taskNode struct {
+	config types.TaskConfig
+	deps   []*taskNode
+	// after are tasks which must complete before this task is started, but
+	// which are not dependencies of the task
+	after []*taskNode
+	done  chan struct{}
+}
+
+func (n *taskNode) waitForDependencies() {
+	for _, dep := range append(n.after, n.deps...) {
+		<-dep.done
+	}
+}
+
+// newTaskGraph returns a node for each unique task in the collection. The
+// nodes are returned in the same order as the collection. A task depends on the
+// tasks from its Dependencies(). A task is also run after every earlier task
+// which sets environment variables, because those variables may be used by any
+// task that follows it.
+func newTaskGraph(tasks *TaskCollection) []*taskNode {
+	nodes := []*taskNode{}
+	envNodes := []*taskNode{}
+
+	find := func(name task.Name) *taskNode {
+		for _, node := range nodes {
+			if node.config.Name().Equal(name) {
+				return node
+			}
+		}
+		return nil
+	}
+
+	for _, taskConfig := range tasks.All() {
+		if find(taskConfig.Name()) != nil {
+			continue
+		}
+		node := &taskNode{
+			config: taskConfig,
+			after:  append([]*taskNode{}, envNodes...),
+			done:   make(chan struct{}),
+		}
+		for _, dep := range taskConfig.Dependencies() {
+			if depNode := find(task.ParseName(dep)); depNode != nil {
+				node.deps = append(node.deps, depNode)
+			}
+		}
+		nodes = append(nodes, node)
+
+		if setsEnvironment(taskConfig) {
+			envNodes = append(envNodes, node)
+		}
+	}
+	return nodes
+}
+
+// setsEnvironment returns true if the task sets environment variables for dobi
+func setsEnvironment(taskConfig types.TaskConfig) bool {
+	if strings.HasPrefix(taskConfig.Name().Action(), "capture") {
+		return true
+	}
+	_, isEnv := taskConfig.Resource().(*config.EnvConfig)
+	return isEnv
+}
+
+var errStoppedAfterFailure = errors.New("not started after another task failed")
+
+// executeTasksParallel runs up to workers tasks at the same time. A task is
+// started once all of its dependencies are complete. After a task fails no new
+// tasks are started, but tasks that are already running are allowed to finish.
+// With Settings.KeepGoing only the tasks which depend on a failed task are
+// skipped.
+func executeTasksParallel(
+	ctx *context.ExecuteContext,
+	tasks *TaskCollection,
+	workers int,
+) error {
+	var (
+		lock         sync.Mutex
+		startedTasks []types.Task
+		wg           sync.WaitGroup
+	)
+	defer func() {
+		stopTasks(ctx, startedTasks)
+	}()
+
+	results := newRunResults()
+	output := newSyncWriter(ctx.Stdout)
+	errOutput := newSyncWriter(ctx.Stderr)
+	semaphore := make(chan struct{}, workers)
+
+	run := func(node *taskNode) {
+		defer close(node.done)
+		node.waitForDependencies()
+
+		semaphore <- struct{}{}
+		defer func() { <-semaphore }()
+		if err := results.failedDependency(node.config); err != nil {
+			skipTask(ctx, results, node.config.Name(), err)
+			return
+		}
+		if !ctx.Settings.KeepGoing && results.firstError() != nil {
+			skipTask(ctx, results, node.config.Name(), errStoppedAfterFailure)
+			return
+		}
+		if ctx.Context.Err() != nil {
+			skipTask(ctx, results, node.config.Name(), errInterrupted)
+			return
+		}
+
+		prefix := node.config.Name().Name()
+		stdout := newPrefixWriter(output, prefix)
+		stderr := newPrefixWriter(errOutput, prefix)
+		defer stdout.Flush()
+		defer stderr.Flush()
+		taskCtx := ctx.WithOutput(stdout, stderr)
+
+		started, _ := executeEnabledTask(taskCtx, results, tasks, node.config)
+		lock.Lock()
+		startedTasks = append(startedTasks, started...)
+		lock.Unlock()
+	}
+
+	ctx.Logger.Debugf("executing tasks with %d workers", workers)
+	for _, node := range newTaskGraph(tasks) {
+		wg.Add(1)
+		go func(node *taskNode) {
+			defer wg.Done()
+			run(node)
+		}(node)
+	}
+	wg.Wait()
+	return results.finish(ctx)
+}
@@ -0,0 +1,187 @@
+package tasks
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/execenv"
+	testconfig "github.com/dnephin/dobi/internal/test/config"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	"github.com/dnephin/dobi/tasks/types"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+type fakeTask struct {
+	types.NoStop
+	name task.Name
+	run  func(*context.ExecuteContext) error
+}
+
+func (t *fakeTask) Name() task.Name {
+	return t.name
+}
+
+func (t *fakeTask) Repr() string {
+	return t.name.Name()
+}
+
+func (t *fakeTask) Run(ctx *context.ExecuteContext, depsModified bool) (bool, error) {
+	return depsModified, t.run(ctx)
+}
+
+func newFakeTaskConfig(
+	name string,
+	deps []string,
+	run func(*context.ExecuteContext) error,
+) types.TaskConfig {
+	taskName := task.ParseName(name)
+	return types.NewTaskConfig(
+		task.NewDefaultName(taskName.Resource(), taskName.Action()),
+		&testconfig.FakeResource{},
+		func() []string { return deps },
+		func(name task.Name, _ config.Resource) types.Task {
+			return &fakeTask{name: name, run: run}
+		})
+}
+
+func newTestCollection(configs ...types.TaskConfig) *TaskCollection {
+	collection := newTaskCollection()
+	for _, taskConfig := range configs {
+		collection.add(taskConfig)
+	}
+	return collection
+}
+
+func nodeNames(nodes []*taskNode) []string {
+	names := []string{}
+	for _, node := range nodes {
+		names = append(names, node.config.Name().Name())
+	}
+	return names
+}
+
+func TestNewTaskGraph(t *testing.T) {
+	noop := func(*context.ExecuteContext) error { return nil }
+	tasks := newTestCollection(
+		newFakeTaskConfig("one:run", nil, noop),
+		newFakeTaskConfig("two:run", []string{"one"}, noop),
+		newFakeTaskConfig("one:run", nil, noop),
+		newFakeTaskConfig("three:run", []string{"one:run", "two"}, noop),
+	)
+
+	nodes := newTaskGraph(tasks)
+	assert.Check(t, is.DeepEqual(
+		[]string{"one:run", "two:run", "three:run"}, nodeNames(nodes)))
+	assert.Check(t, is.Len(nodes[0].deps, 0))
+	assert.Check(t, is.DeepEqual([]string{"one:run"}, nodeNames(nodes[1].deps)))
+	assert.Check(t, is.DeepEqual(
+		[]string{"one:run", "two:run"}, nodeNames(nodes[2].deps)))
+}
+
+func TestNewTaskGraphDependsOnEnvironmentTasks(t *testing.T) {
+	noop := func(*context.ExecuteContext) error { return nil }
+	tasks := newTestCollection(
+		newFakeTaskConfig("version:capture(VERSION)", nil, noop),
+		newFakeTaskConfig("release:run", nil, noop),
+	)
+
+	nodes := newTaskGraph(tasks)
+	assert.Check(t, is.Len(nodes[1].deps, 0))
+	assert.Check(t, is.DeepEqual(
+		[]string{"version:capture(VERSION)"}, nodeNames(nodes[1].after)))
+}
+
+func newTestContext() *context.ExecuteContext {
+	conf := &config.Config{WorkingDir: "/work"}
+	execEnv := execenv.NewExecEnv("exec", "project", conf.WorkingDir)
+	ctx := context.NewExecuteContext(conf, nil, execEnv, context.Settings{})
+	ctx.Stdout = &bytes.Buffer{}
+	ctx.Stderr = &bytes.Buffer{}
+	return ctx
+}
+
+func TestExecuteTasksParallel(t *testing.T) {
+	var lock sync.Mutex
+	order := []string{}
+	record := func(name string) func(*context.ExecuteContext) error {
+		return func(ctx *context.ExecuteContext) error {
+			lock.Lock()
+			defer lock.Unlock()
+			order = append(order, name)
+			fmt.Fprintf(ctx.Stdout, "output from %s\n", name)
+			return nil
+		}
+	}
+	tasks := newTestCollection(
+		newFakeTaskConfig("one:run", nil, record("one")),
+		newFakeTaskConfig("two:run", []string{"one"}, record("two")),
+		newFakeTaskConfig("three:run", []string{"one"}, record("three")),
+		newFakeTaskConfig("four:run", []string{"two", "three"}, record("four")),
+	)
+
+	ctx := newTestContext()
+	err := executeTasksParallel(ctx, tasks, 2)
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(order, 4))
+	assert.Check(t, is.Equal("one", order[0]))
+	assert.Check(t, is.Equal("four", order[3]))
+	assert.Check(t, is.Contains(
+		ctx.Stdout.(*bytes.Buffer).String(), "[four:run] output from four\n"))
+}
+
+func TestExecuteTasksParallelStopsAfterFailure(t *testing.T) {
+	var ran bool
+	tasks := newTestCollection(
+		newFakeTaskConfig("one:run", nil, func(*context.ExecuteContext) error {
+			return fmt.Errorf("broken")
+		}),
+		newFakeTaskConfig("two:run", []string{"one"}, func(*context.ExecuteContext) error {
+			ran = true
+			return nil
+		}),
+	)
+
+	err := executeTasksParallel(newTestContext(), tasks, 2)
+	assert.Check(t, is.Error(err, `failed to execute task "one:run": broken`))
+	assert.Check(t, !ran, "expected dependent task to be skipped")
+}
+
+func TestExecuteTasksParallelDoesNotOverlapRunsOfHook(t *testing.T) {
+	var lock sync.Mutex
+	running, maxRunning, runs := 0, 0, 0
+	runDB := func(*context.ExecuteContext) error {
+		lock.Lock()
+		running++
+		runs++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		lock.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		lock.Lock()
+		running--
+		lock.Unlock()
+		return nil
+	}
+	noop := func(*context.ExecuteContext) error { return nil }
+	test := newFakeTaskConfig("test:run", nil, noop)
+	tasks := newTestCollection(newFakeTaskConfig("db:run", nil, runDB), test)
+	tasks.hooks[test.Name().MapKey()] = &taskHooks{
+		before:    newTestCollection(newFakeTaskConfig("db:run", nil, runDB)),
+		after:     newTestCollection(),
+		onFailure: newTestCollection(),
+	}
+
+	err := executeTasksParallel(newTestContext(), tasks, 2)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(2, runs))
+	assert.Check(t, is.Equal(1, maxRunning))
+}
@@ -0,0 +1,239 @@
+package tasks
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/dnephin/dobi/execenv"
+	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	"github.com/dnephin/dobi/tasks/types"
+)
+
+// planStep is the expected outcome of running a task
+type planStep struct {
+	name      task.Name
+	staleness types.Staleness
+	modified  bool
+	// skipped is true when the when condition of the task is false
+	skipped bool
+	// unknown is true when the task uses a variable which does not have a
+	// value until another task runs
+	unknown bool
+	err     error
+	// hook describes the task which runs this task as a hook, like
+	// "before test:run"
+	hook string
+}
+
+func (s planStep) verdict() string {
+	switch {
+	case s.unknown:
+		return "unknown"
+	case s.err != nil:
+		return "error"
+	case s.skipped:
+		return "skip"
+	case s.staleness.Stale:
+		return "stale"
+	default:
+		return "fresh"
+	}
+}
+
+// label returns the name of the task, and the task which runs it as a hook
+func (s planStep) label() string {
+	if s.hook == "" {
+		return s.name.Name()
+	}
+	return fmt.Sprintf("%s (%s)", s.name.Name(), s.hook)
+}
+
+func (s planStep) reason() string {
+	if s.err != nil {
+		return s.err.Error()
+	}
+	return s.staleness.Reason
+}
+
+// planTasks resolves every task and checks if it is stale without running it.
+// Tasks that do not implement types.StaleChecker are always expected to run.
+// A task which is expected to modify its resource is marked as modified, so the
+// tasks which depend on it are also stale. Planning has no side effects, the
+// variables set by env and capture tasks are recorded in the ExecEnv instead of
+// the environment of the process.
+func planTasks(ctx *context.ExecuteContext, tasks *TaskCollection) []planStep {
+	steps := []planStep{}
+	for _, node := range newTaskGraph(tasks) {
+		steps = planWithHooks(ctx, tasks, node.config, "", steps)
+	}
+	return steps
+}
+
+// planWithHooks appends the steps for the task, and for the before and after
+// hooks of the task, to steps. The on-failure hooks are not included because
+// they only run when a task fails.
+func planWithHooks(
+	ctx *context.ExecuteContext,
+	tasks *TaskCollection,
+	taskConfig types.TaskConfig,
+	hook string,
+	steps []planStep,
+) []planStep {
+	name := taskConfig.Name()
+	hooks := tasks.hooksFor(name)
+	if enabled, _, err := checkCondition(ctx, taskConfig); err != nil || !enabled {
+		hooks = nil
+	}
+
+	if hooks != nil {
+		steps = planHooks(ctx, tasks, hooks.before, "before "+name.Name(), steps)
+	}
+	step := planTask(ctx, taskConfig)
+	step.hook = hook
+	if step.modified {
+		ctx.SetModified(name)
+	}
+	steps = append(steps, step)
+	if hooks != nil {
+		steps = planHooks(ctx, tasks, hooks.after, "after "+name.Name(), steps)
+	}
+	return steps
+}
+
+// planHooks appends the steps for the tasks of a hook. Like a run, the
+// dependencies of the tasks named by the hook are not planned again when they
+// are already in the plan.
+func planHooks(
+	ctx *context.ExecuteContext,
+	tasks *TaskCollection,
+	hook *TaskCollection,
+	label string,
+	steps []planStep,
+) []planStep {
+	for _, taskConfig := range hook.All() {
+		if !hook.named[taskConfig.Name().MapKey()] && isPlanned(steps, taskConfig.Name()) {
+			continue
+		}
+		steps = planWithHooks(ctx, tasks, taskConfig, label, steps)
+	}
+	return steps
+}
+
+// isPlanned returns true if the task is in the plan without an error
+func isPlanned(steps []planStep, name task.Name) bool {
+	for _, step := range steps {
+		if step.name.Equal(name) && step.err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// nolint: gocyclo
+func planTask(ctx *context.ExecuteContext, taskConfig types.TaskConfig) planStep {
+	step := planStep{name: taskConfig.Name()}
+
+	enabled, reason, err := checkCondition(ctx, taskConfig)
+	if err != nil {
+		step.err, step.modified = err, true
+		return step
+	}
+
+	currentTask, err := startTask(ctx, taskConfig)
+	if err != nil {
+		var unknown *execenv.UnknownValueError
+		step.unknown = errors.As(err, &unknown)
+		step.err, step.modified = err, true
+		return step
+	}
+	if !enabled {
+		step.skipped, step.staleness = true, types.Fresh(reason)
+		return step
+	}
+
+	if planner, ok := currentTask.(types.Planner); ok {
+		// Variables are recorded so that tasks which follow can resolve
+		// variables which use them.
+		if err := planner.Plan(ctx); err != nil {
+			step.err, step.modified = err, true
+			return step
+		}
+	}
+
+	modifiedDeps := modifiedDependencies(ctx, taskConfig.Dependencies())
+	checker, ok := currentTask.(types.StaleChecker)
+	switch {
+	case len(modifiedDeps) > 0:
+		step.staleness = types.Stale("dependencies are stale")
+		for _, dep := range modifiedDeps {
+			step.staleness = step.staleness.Explain(
+				fmt.Sprintf("dependency: %s is stale", dep))
+		}
+	case !ok:
+		// Tasks without a staleness check always run, and may modify their
+		// resource.
+		step.staleness = types.Stale("always runs")
+	default:
+		step.staleness, step.err = checker.IsStale(ctx)
+	}
+	step.modified = step.staleness.Stale || step.err != nil
+	logging.ForTask(ctx.Logger, currentTask).Debugf("plan: %s %s", step.verdict(), step.reason())
+	return step
+}
+
+// Explain checks if each task is stale without running it, and prints the
+// reason along with the details of the check. The dependencies of each task are
+// included, because a task is stale when any of its dependencies are stale.
+func Explain(options RunOptions) error {
+	options.Tasks = getNames(options)
+	if len(options.Tasks) == 0 {
+		return fmt.Errorf("no task to explain, and no default task defined")
+	}
+
+	execEnv, err := execenv.NewExecEnvFromConfig(
+		options.Config.Meta.ExecID,
+		options.Config.Meta.Project,
+		options.Config.WorkingDir,
+		getLogger(options),
+	)
+	if err != nil {
+		return err
+	}
+
+	tasks, err := collectTasks(options)
+	if err != nil {
+		return err
+	}
+
+	settings := context.NewSettings(options.Quiet, options.BindMount)
+	ctx := context.NewExecuteContext(options.Config, options.Client, execEnv, settings)
+	ctx.Logger = getLogger(options)
+	printExplanation(ctx.Stdout, planTasks(ctx, tasks))
+	return nil
+}
+
+func printExplanation(out io.Writer, steps []planStep) {
+	for _, step := range steps {
+		verdict := "is " + step.verdict()
+		switch {
+		case step.err != nil && !step.unknown:
+			verdict = "failed"
+		case step.skipped:
+			verdict = "is skipped"
+		}
+		fmt.Fprintf(out, "%s %s: %s\n", step.label(), verdict, step.reason())
+		for _, detail := range step.staleness.Details {
+			fmt.Fprintf(out, "    %s\n", detail)
+		}
+	}
+}
+
+func printPlan(out io.Writer, steps []planStep) {
+	fmt.Fprintln(out, "Plan:")
+	for _, step := range steps {
+		fmt.Fprintf(out, "  %-30s %-6s %s\n", step.label(), step.verdict(), step.reason())
+	}
+}
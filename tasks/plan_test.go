@@ -0,0 +1,221 @@
+package tasks
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	testconfig "github.com/dnephin/dobi/internal/test/config"
+	"github.com/dnephin/dobi/tasks/alias"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/env"
+	"github.com/dnephin/dobi/tasks/task"
+	"github.com/dnephin/dobi/tasks/types"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+type fakeCheckedTask struct {
+	fakeTask
+	staleness types.Staleness
+}
+
+func (t *fakeCheckedTask) IsStale(_ *context.ExecuteContext) (types.Staleness, error) {
+	return t.staleness, nil
+}
+
+func newFakeCheckedTaskConfig(
+	name string,
+	deps []string,
+	staleness types.Staleness,
+) types.TaskConfig {
+	return types.NewTaskConfig(
+		task.NewDefaultName(name, "run"),
+		&testconfig.FakeResource{},
+		func() []string { return deps },
+		func(name task.Name, _ config.Resource) types.Task {
+			return &fakeCheckedTask{fakeTask: fakeTask{name: name}, staleness: staleness}
+		})
+}
+
+func TestPlanTasks(t *testing.T) {
+	ran := false
+	tasks := newTestCollection(
+		newFakeCheckedTaskConfig("image", nil, types.Stale("image does not exist")),
+		newFakeCheckedTaskConfig("source", nil, types.Fresh("mount exists")),
+		newFakeCheckedTaskConfig("build", []string{"image", "source"},
+			types.Fresh("artifact newer than sources")),
+		newFakeCheckedTaskConfig("docs", []string{"source"},
+			types.Fresh("artifact newer than sources")),
+		newFakeTaskConfig("all:run", []string{"build", "docs"},
+			func(*context.ExecuteContext) error {
+				ran = true
+				return nil
+			}),
+	)
+
+	out := new(bytes.Buffer)
+	printPlan(out, planTasks(newTestContext(), tasks))
+	assert.Check(t, !ran, "expected tasks to not run")
+	expected := `Plan:
+  image:run                      stale  image does not exist
+  source:run                     fresh  mount exists
+  build:run                      stale  dependencies are stale
+  docs:run                       fresh  artifact newer than sources
+  all:run                        stale  dependencies are stale
+`
+	assert.Check(t, is.Equal(expected, out.String()))
+}
+
+func TestPrintExplanation(t *testing.T) {
+	tasks := newTestCollection(
+		newFakeCheckedTaskConfig("image", nil,
+			types.Stale("Image record older than context").Explain(
+				"newest context file: Dockerfile modified 2020-01-02 03:04:05.000 UTC")),
+		newFakeCheckedTaskConfig("source", nil, types.Fresh("mount exists")),
+		newFakeCheckedTaskConfig("build", []string{"image", "source"},
+			types.Fresh("artifact newer than sources")),
+	)
+
+	out := new(bytes.Buffer)
+	printExplanation(out, planTasks(newTestContext(), tasks))
+	expected := `image:run is stale: Image record older than context
+    newest context file: Dockerfile modified 2020-01-02 03:04:05.000 UTC
+source:run is fresh: mount exists
+build:run is stale: dependencies are stale
+    dependency: image is stale
+`
+	assert.Check(t, is.Equal(expected, out.String()))
+}
+
+type fakeCaptureTask struct {
+	fakeCheckedTask
+	variable string
+}
+
+func (t *fakeCaptureTask) Plan(ctx *context.ExecuteContext) error {
+	ctx.Env.SetUnknownVariable(t.variable, t.name.Name())
+	return nil
+}
+
+type resolvingResource struct {
+	testconfig.FakeResource
+	value string
+}
+
+func (r *resolvingResource) Resolve(resolver config.Resolver) (config.Resource, error) {
+	resolved := *r
+	var err error
+	resolved.value, err = resolver.Resolve(r.value)
+	return &resolved, err
+}
+
+func newResolvingTaskConfig(name string, deps []string, value string) types.TaskConfig {
+	return types.NewTaskConfig(
+		task.NewDefaultName(name, "run"),
+		&resolvingResource{value: value},
+		func() []string { return deps },
+		func(name task.Name, _ config.Resource) types.Task {
+			return &fakeCheckedTask{
+				fakeTask:  fakeTask{name: name},
+				staleness: types.Fresh("artifact newer than sources"),
+			}
+		})
+}
+
+func TestPlanTasksDoesNotSetVariables(t *testing.T) {
+	envConfig, err := env.GetTaskConfig("vars", "", &config.EnvConfig{
+		Variables: []string{"DOBI_TEST_PLAN_VERSION=1.2.3"},
+	})
+	assert.NilError(t, err)
+	captureConfig := types.NewTaskConfig(
+		task.NewName("version", "capture(DOBI_TEST_PLAN_COMMIT)"),
+		&testconfig.FakeResource{},
+		task.NoDependencies,
+		func(name task.Name, _ config.Resource) types.Task {
+			return &fakeCaptureTask{
+				fakeCheckedTask: fakeCheckedTask{
+					fakeTask:  fakeTask{name: name},
+					staleness: types.Stale("always captures"),
+				},
+				variable: "DOBI_TEST_PLAN_COMMIT",
+			}
+		})
+	aliasConfig, err := alias.GetTaskConfig("all", "", &config.AliasConfig{
+		Tasks: []string{"build"},
+	})
+	assert.NilError(t, err)
+
+	tasks := newTestCollection(
+		envConfig,
+		captureConfig,
+		newResolvingTaskConfig("build", nil, "image:{env.DOBI_TEST_PLAN_VERSION}"),
+		newResolvingTaskConfig("push", nil, "image:{env.DOBI_TEST_PLAN_COMMIT}"),
+		aliasConfig,
+	)
+
+	ctx := newTestContext()
+	out := new(bytes.Buffer)
+	printPlan(out, planTasks(ctx, tasks))
+	expected := `Plan:
+  vars:set                       stale  DOBI_TEST_PLAN_VERSION will be changed
+  version:capture(DOBI_TEST_PLAN_COMMIT) stale  always captures
+  build:run                      fresh  artifact newer than sources
+  push:run                       unknown the value of "DOBI_TEST_PLAN_COMMIT" is not ` +
+		`known until version:capture(DOBI_TEST_PLAN_COMMIT) runs
+  all:run                        fresh  dependencies are fresh
+`
+	assert.Check(t, is.Equal(expected, out.String()))
+
+	_, isSet := os.LookupEnv("DOBI_TEST_PLAN_VERSION")
+	assert.Check(t, !isSet, "expected the plan to not set variables")
+	value, err := ctx.Env.Resolve("{env.DOBI_TEST_PLAN_VERSION}")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal("1.2.3", value))
+}
+
+func TestPlanTasksIncludesHooks(t *testing.T) {
+	test := newFakeCheckedTaskConfig("test", nil, types.Stale("sources changed"))
+	tasks := newTestCollection(test)
+	tasks.hooks[test.Name().MapKey()] = &taskHooks{
+		before: newTestCollection(
+			newFakeCheckedTaskConfig("db", nil, types.Fresh("container is running"))),
+		after: newTestCollection(
+			newFakeCheckedTaskConfig("report", nil, types.Stale("always runs"))),
+		onFailure: newTestCollection(
+			newFakeCheckedTaskConfig("logs", nil, types.Stale("always runs"))),
+	}
+
+	out := new(bytes.Buffer)
+	printPlan(out, planTasks(newTestContext(), tasks))
+	expected := `Plan:
+  db:run (before test:run)       fresh  container is running
+  test:run                       stale  sources changed
+  report:run (after test:run)    stale  always runs
+`
+	assert.Check(t, is.Equal(expected, out.String()))
+}
+
+func TestPlanTasksDoesNotPlanDependenciesOfHooksAgain(t *testing.T) {
+	build := newFakeCheckedTaskConfig("build", nil, types.Fresh("image is up to date"))
+	test := newFakeCheckedTaskConfig("test", []string{"build"}, types.Stale("sources changed"))
+	tasks := newTestCollection(build, test)
+	db := newFakeCheckedTaskConfig("db", []string{"build"}, types.Fresh("container is running"))
+	hook := newTestCollection(build, db)
+	hook.named = map[string]bool{db.Name().MapKey(): true}
+	tasks.hooks[test.Name().MapKey()] = &taskHooks{
+		before:    hook,
+		after:     newTestCollection(),
+		onFailure: newTestCollection(),
+	}
+
+	out := new(bytes.Buffer)
+	printPlan(out, planTasks(newTestContext(), tasks))
+	expected := `Plan:
+  build:run                      fresh  image is up to date
+  db:run (before test:run)       fresh  container is running
+  test:run                       stale  sources changed
+`
+	assert.Check(t, is.Equal(expected, out.String()))
+}
@@ -0,0 +1,95 @@
+package plugin
+
+import (
+	"bytes"
+	gocontext "context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/dnephin/dobi/config"
+)
+
+// Plugin commands
+const (
+	commandValidate     = "validate"
+	commandDependencies = "dependencies"
+	commandResolve      = "resolve"
+	commandRun          = "run"
+	commandStop         = "stop"
+)
+
+// Request is sent to a plugin on stdin
+type Request struct {
+	Command              string                 `json:"command"`
+	Name                 string                 `json:"name"`
+	Action               string                 `json:"action,omitempty"`
+	Config               map[string]interface{} `json:"config"`
+	WorkingDir           string                 `json:"working-dir,omitempty"`
+	DependenciesModified bool                   `json:"dependencies-modified,omitempty"`
+}
+
+// Response is read from the stdout of a plugin
+type Response struct {
+	// Error is the reason the command failed
+	Error string `json:"error,omitempty"`
+	// Dependencies is the list of tasks the resource depends on, returned by
+	// the dependencies command
+	Dependencies []string `json:"dependencies,omitempty"`
+	// Config is the resolved config, returned by the resolve command
+	Config map[string]interface{} `json:"config,omitempty"`
+	// Modified is true when the run command modified the resource
+	Modified bool `json:"modified,omitempty"`
+}
+
+// call runs the plugin with the request. The executable is run with the command
+// as the only argument, receives the request as JSON on stdin, and writes the
+// response as JSON to stdout. The stderr of the plugin is written to stderr. A
+// non-zero exit code, or an error in the response, is returned as an error.
+func call(
+	gctx gocontext.Context,
+	conf *config.PluginConfig,
+	request Request,
+	stderr io.Writer,
+) (Response, error) {
+	var response Response
+	request.Config = conf.Values
+	input, err := json.Marshal(request)
+	if err != nil {
+		return response, fmt.Errorf("failed to encode %s request: %s", request.Command, err)
+	}
+
+	stdout := new(bytes.Buffer)
+	cmd := exec.CommandContext(gctx, conf.Executable, request.Command)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	runErr := cmd.Run()
+
+	if stdout.Len() > 0 {
+		if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+			return response, fmt.Errorf(
+				"%s %s returned an invalid response: %s", conf.Type, request.Command, err)
+		}
+	}
+	switch {
+	case response.Error != "":
+		return response, fmt.Errorf("%s", response.Error)
+	case runErr != nil:
+		return response, fmt.Errorf("%s %s failed: %s", conf.Type, request.Command, runErr)
+	}
+	return response, nil
+}
+
+// callBeforeRun calls the plugin before any task is run, with the stderr of the
+// plugin included in the error
+func callBeforeRun(conf *config.PluginConfig, request Request) (Response, error) {
+	stderr := new(bytes.Buffer)
+	response, err := call(gocontext.Background(), conf, request, stderr)
+	if err != nil && stderr.Len() > 0 {
+		err = fmt.Errorf("%s\n%s", err, strings.TrimSpace(stderr.String()))
+	}
+	return response, err
+}
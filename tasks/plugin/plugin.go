@@ -0,0 +1,118 @@
+// Package plugin provides the tasks for resources with a type provided by an
+// external executable.
+package plugin
+
+import (
+	"fmt"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	"github.com/dnephin/dobi/tasks/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// GetTaskConfig returns a new task for the action. The plugin decides which
+// actions are valid when the task is run. The plugin validates the config and
+// returns the dependencies of the task, except for the remove action which
+// has no dependencies.
+func GetTaskConfig(name, action string, conf *config.PluginConfig) (types.TaskConfig, error) {
+	switch action {
+	case "remove", "rm":
+		return types.NewTaskConfig(
+			task.NewName(name, action), conf, task.NoDependencies, newTask), nil
+	}
+
+	deps, err := dependencies(name, conf)
+	if err != nil {
+		return nil, fmt.Errorf("resource %q: %s", name, err)
+	}
+	taskName := task.NewName(name, action)
+	if action == "" || action == "run" {
+		taskName = task.NewDefaultName(name, "run")
+	}
+	return types.NewTaskConfig(taskName, conf, deps, newTask), nil
+}
+
+// dependencies calls the plugin to validate the config, and returns the tasks
+// listed in depends and the dependencies returned by the plugin
+func dependencies(name string, conf *config.PluginConfig) (func() []string, error) {
+	if _, err := callBeforeRun(conf, Request{Command: commandValidate, Name: name}); err != nil {
+		return nil, err
+	}
+	response, err := callBeforeRun(conf, Request{Command: commandDependencies, Name: name})
+	if err != nil {
+		return nil, err
+	}
+	deps := append(append([]string{}, conf.Dependencies()...),
+		conf.QualifyNames(response.Dependencies)...)
+	return func() []string {
+		return deps
+	}, nil
+}
+
+// Task runs an action of a plugin resource
+type Task struct {
+	name    task.Name
+	config  *config.PluginConfig
+	started bool
+}
+
+func newTask(name task.Name, conf config.Resource) types.Task {
+	return &Task{name: name, config: conf.(*config.PluginConfig)}
+}
+
+// Name returns the name of the task
+func (t *Task) Name() task.Name {
+	return t.name
+}
+
+func (t *Task) logger(ctx *context.ExecuteContext) *log.Entry {
+	return logging.ForTask(ctx.Logger, t)
+}
+
+// Repr formats the task for logging
+func (t *Task) Repr() string {
+	return t.name.Format(t.config.Type)
+}
+
+func (t *Task) request(ctx *context.ExecuteContext, command string) Request {
+	return Request{
+		Command:    command,
+		Name:       t.name.Resource(),
+		Action:     t.name.Action(),
+		WorkingDir: ctx.WorkingDir,
+	}
+}
+
+// Run the action with the plugin, after the plugin resolves the config
+func (t *Task) Run(ctx *context.ExecuteContext, depsModified bool) (bool, error) {
+	resolved, err := call(ctx.Context, t.config, t.request(ctx, commandResolve), ctx.Stderr)
+	if err != nil {
+		return false, err
+	}
+	if resolved.Config != nil {
+		t.config.Values = resolved.Config
+	}
+
+	request := t.request(ctx, commandRun)
+	request.DependenciesModified = depsModified
+	t.started = true
+	response, err := call(ctx.Context, t.config, request, ctx.Stderr)
+	if err != nil {
+		return false, err
+	}
+	t.logger(ctx).Info("Done")
+	return response.Modified, nil
+}
+
+// Stop the action with the plugin, if it was run
+func (t *Task) Stop(ctx *context.ExecuteContext) error {
+	if !t.started {
+		return nil
+	}
+	t.logger(ctx).Debug("Stop")
+	_, err := call(ctx.Context, t.config, t.request(ctx, commandStop), ctx.Stderr)
+	return err
+}
@@ -0,0 +1,89 @@
+package plugin
+
+import (
+	"bytes"
+	"runtime"
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/skip"
+)
+
+const fakePlugin = `#!/bin/sh
+read request
+echo "$1 release" >&2
+case "$1" in
+dependencies) echo '{"dependencies": ["db"]}' ;;
+resolve) echo '{"config": {"release": "resolved"}}' ;;
+run) echo "$request" | grep -q '"release":"resolved"' && echo '{"modified": true}' ;;
+stop) echo '{"error": "failed to stop"}' ;;
+esac
+`
+
+const invalidPlugin = `#!/bin/sh
+echo "chart is required" >&2
+exit 3
+`
+
+func setupPlugins(t *testing.T) *fs.Dir {
+	skip.If(t, runtime.GOOS == "windows", "plugin is a shell script")
+	return fs.NewDir(t, "plugins",
+		fs.WithFile("dobi-resource-helm", fakePlugin, fs.WithMode(0755)),
+		fs.WithFile("dobi-resource-invalid", invalidPlugin, fs.WithMode(0755)))
+}
+
+func TestGetTaskConfig(t *testing.T) {
+	dir := setupPlugins(t)
+	defer dir.Remove()
+
+	conf := &config.PluginConfig{Type: "helm", Executable: dir.Join("dobi-resource-helm")}
+	conf.Depends = []string{"setup"}
+	taskConfig, err := GetTaskConfig("chart", "", conf)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal("chart:run", taskConfig.Name().Name()))
+	assert.Check(t, is.DeepEqual([]string{"setup", "db"}, taskConfig.Dependencies()))
+
+	taskConfig, err = GetTaskConfig("chart", "rm", conf)
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(taskConfig.Dependencies(), 0))
+}
+
+func TestGetTaskConfigWithInvalidConfig(t *testing.T) {
+	dir := setupPlugins(t)
+	defer dir.Remove()
+
+	conf := &config.PluginConfig{Type: "invalid", Executable: dir.Join("dobi-resource-invalid")}
+	_, err := GetTaskConfig("chart", "", conf)
+	assert.Check(t, is.Error(err,
+		"resource \"chart\": invalid validate failed: exit status 3\nchart is required"))
+}
+
+func TestTaskRunAndStop(t *testing.T) {
+	dir := setupPlugins(t)
+	defer dir.Remove()
+
+	conf := &config.PluginConfig{
+		Type:       "helm",
+		Executable: dir.Join("dobi-resource-helm"),
+		Values:     map[string]interface{}{"release": "{project}"},
+	}
+	plugin := newTask(task.NewName("chart", "run"), conf)
+	stderr := new(bytes.Buffer)
+	ctx := context.NewExecuteContext(config.NewConfig(), nil, nil, context.Settings{})
+	ctx.Stderr = stderr
+
+	assert.NilError(t, plugin.Stop(ctx))
+	modified, err := plugin.Run(ctx, false)
+	assert.NilError(t, err)
+	assert.Check(t, modified)
+
+	err = plugin.Stop(ctx)
+	assert.Check(t, is.Error(err, "failed to stop"))
+	expected := "resolve release\nrun release\nstop release\n"
+	assert.Check(t, is.Equal(expected, stderr.String()))
+}
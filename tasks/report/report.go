@@ -0,0 +1,164 @@
+// Package report records the outcome of each task in a run, and writes the
+// outcomes as a JUnit XML or JSON report.
+package report
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/dnephin/dobi/tasks/events"
+)
+
+// Status of a task in the report
+type Status string
+
+// The status of each task
+const (
+	Succeeded Status = "succeeded"
+	Fresh     Status = "fresh"
+	Failed    Status = "failed"
+	Skipped   Status = "skipped"
+)
+
+// TaskReport is the outcome of a single task
+type TaskReport struct {
+	Name     string        `json:"name"`
+	Status   Status        `json:"status"`
+	Duration time.Duration `json:"-"`
+	Seconds  float64       `json:"duration"`
+	Reason   string        `json:"reason,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Report is an events.Listener which records the outcome of each task. Tasks
+// which are collected but never started are skipped.
+type Report struct {
+	Tasks []*TaskReport `json:"tasks"`
+}
+
+// New returns a new empty Report
+func New() *Report {
+	return &Report{Tasks: []*TaskReport{}}
+}
+
+func (r *Report) get(name string) *TaskReport {
+	for _, task := range r.Tasks {
+		if task.Name == name {
+			return task
+		}
+	}
+	task := &TaskReport{Name: name}
+	r.Tasks = append(r.Tasks, task)
+	return task
+}
+
+// Handle records the event in the report
+func (r *Report) Handle(event events.Event) {
+	switch event.Type {
+	case events.Collected:
+		task := r.get(event.Task.Name())
+		task.Status = Skipped
+		task.Reason = "not run"
+	case events.Started:
+		task := r.get(event.Task.Name())
+		task.Status = Succeeded
+		task.Reason = ""
+	case events.Fresh:
+		task := r.get(event.Task.Name())
+		task.Status = Fresh
+		task.Reason = event.Reason
+	case events.Stale:
+		r.get(event.Task.Name()).Reason = event.Reason
+	case events.Finished:
+		task := r.get(event.Task.Name())
+		task.Duration = event.Duration
+		task.Seconds = event.Duration.Seconds()
+		if event.Err != nil {
+			task.Status = Failed
+			task.Error = event.Err.Error()
+		}
+	case events.Skipped:
+		task := r.get(event.Task.Name())
+		task.Status = Skipped
+		task.Reason = event.Reason
+	}
+}
+
+// WriteJSON writes the report as JSON
+func (r *Report) WriteJSON(out io.Writer) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+// WriteJUnit writes the report as JUnit XML. Each task is a test case. Fresh
+// tasks pass, with the reason they are fresh in the output of the test case.
+func (r *Report) WriteJUnit(out io.Writer) error {
+	suite := junitTestSuite{Name: "dobi", Tests: len(r.Tasks)}
+	var total time.Duration
+	for _, task := range r.Tasks {
+		total += task.Duration
+		testCase := junitTestCase{
+			Name:      task.Name,
+			ClassName: "dobi",
+			Time:      formatSeconds(task.Duration),
+		}
+		switch task.Status {
+		case Failed:
+			suite.Failures++
+			testCase.Failure = &junitMessage{Message: task.Error}
+		case Skipped:
+			suite.Skipped++
+			testCase.Skipped = &junitMessage{Message: task.Reason}
+		case Fresh:
+			testCase.SystemOut = "fresh: " + task.Reason
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+	suite.Time = formatSeconds(total)
+
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(out)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(out, "\n")
+	return err
+}
+
+func formatSeconds(duration time.Duration) string {
+	return strconv.FormatFloat(duration.Seconds(), 'f', 3, 64)
+}
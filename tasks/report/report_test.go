@@ -0,0 +1,98 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dnephin/dobi/tasks/events"
+	"github.com/dnephin/dobi/tasks/task"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func newTestReport() *Report {
+	build := task.NewDefaultName("builder", "build")
+	test := task.NewDefaultName("test", "run")
+	lint := task.NewDefaultName("lint", "run")
+	docs := task.NewDefaultName("docs", "run")
+
+	report := New()
+	for _, event := range []events.Event{
+		events.NewEvent(events.Collected, build),
+		events.NewEvent(events.Collected, test),
+		events.NewEvent(events.Collected, lint),
+		events.NewEvent(events.Collected, docs),
+		events.NewEvent(events.Started, build),
+		events.NewStalenessEvent(build, false, "Image record newer than context"),
+		events.NewFinishedEvent(build, 250*time.Millisecond, false, nil),
+		events.NewEvent(events.Started, test),
+		events.NewStalenessEvent(test, true, "job has no artifact"),
+		events.NewFinishedEvent(test, 2*time.Second, true, fmt.Errorf("exit 1")),
+		{Type: events.Skipped, Task: lint, Reason: "dependency test:run failed"},
+	} {
+		report.Handle(event)
+	}
+	return report
+}
+
+func TestWriteJUnit(t *testing.T) {
+	out := new(bytes.Buffer)
+	assert.NilError(t, newTestReport().WriteJUnit(out))
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="dobi" tests="4" failures="1" skipped="2" time="2.250">
+    <testcase name="builder:build" classname="dobi" time="0.250">
+      <system-out>fresh: Image record newer than context</system-out>
+    </testcase>
+    <testcase name="test:run" classname="dobi" time="2.000">
+      <failure message="exit 1"></failure>
+    </testcase>
+    <testcase name="lint:run" classname="dobi" time="0.000">
+      <skipped message="dependency test:run failed"></skipped>
+    </testcase>
+    <testcase name="docs:run" classname="dobi" time="0.000">
+      <skipped message="not run"></skipped>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+	assert.Check(t, is.Equal(expected, out.String()))
+}
+
+func TestWriteJSON(t *testing.T) {
+	out := new(bytes.Buffer)
+	assert.NilError(t, newTestReport().WriteJSON(out))
+	expected := `{
+  "tasks": [
+    {
+      "name": "builder:build",
+      "status": "fresh",
+      "duration": 0.25,
+      "reason": "Image record newer than context"
+    },
+    {
+      "name": "test:run",
+      "status": "failed",
+      "duration": 2,
+      "reason": "job has no artifact",
+      "error": "exit 1"
+    },
+    {
+      "name": "lint:run",
+      "status": "skipped",
+      "duration": 0,
+      "reason": "dependency test:run failed"
+    },
+    {
+      "name": "docs:run",
+      "status": "skipped",
+      "duration": 0,
+      "reason": "not run"
+    }
+  ]
+}
+`
+	assert.Check(t, is.Equal(expected, out.String()))
+}
@@ -0,0 +1,102 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/dnephin/dobi/tasks/events"
+)
+
+// Trace is an events.Listener which records a span for each task, and for each
+// step of a task. The spans are written in the Chrome Trace Event format, which
+// can be loaded by Perfetto or chrome://tracing.
+type Trace struct {
+	start   time.Time
+	threads map[string]int
+	spans   []traceEvent
+}
+
+// traceEvent is a complete ("X") or metadata ("M") event in the Chrome Trace
+// Event format. Times are in microseconds.
+type traceEvent struct {
+	Name      string            `json:"name"`
+	Category  string            `json:"cat,omitempty"`
+	Phase     string            `json:"ph"`
+	Timestamp int64             `json:"ts"`
+	Duration  int64             `json:"dur,omitempty"`
+	ProcessID int               `json:"pid"`
+	ThreadID  int               `json:"tid"`
+	Args      map[string]string `json:"args,omitempty"`
+}
+
+type traceFile struct {
+	TraceEvents     []traceEvent `json:"traceEvents"`
+	DisplayTimeUnit string       `json:"displayTimeUnit"`
+}
+
+// NewTrace returns a new empty Trace
+func NewTrace() *Trace {
+	return &Trace{threads: make(map[string]int)}
+}
+
+// thread returns the id of the thread for the task. Each task is shown on its
+// own row, so that tasks which run at the same time do not overlap.
+func (t *Trace) thread(name string) int {
+	if tid, ok := t.threads[name]; ok {
+		return tid
+	}
+	tid := len(t.threads) + 1
+	t.threads[name] = tid
+	t.spans = append(t.spans, traceEvent{
+		Name:      "thread_name",
+		Phase:     "M",
+		ProcessID: 1,
+		ThreadID:  tid,
+		Args:      map[string]string{"name": name},
+	})
+	return tid
+}
+
+// Handle records a span for Finished and SpanFinished events
+func (t *Trace) Handle(event events.Event) {
+	if t.start.IsZero() {
+		t.start = event.Time
+	}
+
+	var span traceEvent
+	switch event.Type {
+	case events.Finished:
+		span = traceEvent{Name: event.Task.Name(), Category: "task"}
+		if event.Err != nil {
+			span.Args = map[string]string{"error": event.Err.Error()}
+		}
+	case events.SpanFinished:
+		span = traceEvent{Name: event.Span, Category: "step"}
+	default:
+		return
+	}
+
+	start := event.Time.Add(-event.Duration)
+	if start.Before(t.start) {
+		start = t.start
+	}
+	span.Phase = "X"
+	span.Timestamp = start.Sub(t.start).Microseconds()
+	span.Duration = event.Duration.Microseconds()
+	span.ProcessID = 1
+	span.ThreadID = t.thread(event.Task.Name())
+	t.spans = append(t.spans, span)
+}
+
+// Write the trace as JSON in the Chrome Trace Event format
+func (t *Trace) Write(out io.Writer) error {
+	spans := t.spans
+	if spans == nil {
+		spans = []traceEvent{}
+	}
+	return json.NewEncoder(out).Encode(traceFile{
+		TraceEvents:     spans,
+		DisplayTimeUnit: "ms",
+	})
+}
@@ -0,0 +1,61 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dnephin/dobi/tasks/events"
+	"github.com/dnephin/dobi/tasks/task"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestTraceWrite(t *testing.T) {
+	build := task.NewDefaultName("builder", "build")
+	test := task.NewDefaultName("test", "run")
+	start := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+
+	stepFinished := events.NewSpanEvent(events.SpanFinished, build, "build image")
+	stepFinished.Time = at(200 * time.Millisecond)
+	stepFinished.Duration = 150 * time.Millisecond
+	buildFinished := events.NewFinishedEvent(build, 250*time.Millisecond, true, nil)
+	buildFinished.Time = at(250 * time.Millisecond)
+	testFinished := events.NewFinishedEvent(test, time.Second, false, fmt.Errorf("exit 1"))
+	testFinished.Time = at(1500 * time.Millisecond)
+
+	stepStarted := events.NewSpanEvent(events.SpanStarted, build, "build image")
+	stepStarted.Time = at(50 * time.Millisecond)
+
+	trace := NewTrace()
+	for _, event := range []events.Event{
+		{Type: events.Collected, Task: build, Time: start},
+		{Type: events.Started, Task: build, Time: start},
+		stepStarted,
+		stepFinished,
+		buildFinished,
+		testFinished,
+	} {
+		trace.Handle(event)
+	}
+
+	out := new(bytes.Buffer)
+	assert.NilError(t, trace.Write(out))
+	expected := `{"traceEvents":[` +
+		`{"name":"thread_name","ph":"M","ts":0,"pid":1,"tid":1,"args":{"name":"builder:build"}},` +
+		`{"name":"build image","cat":"step","ph":"X","ts":50000,"dur":150000,"pid":1,"tid":1},` +
+		`{"name":"builder:build","cat":"task","ph":"X","ts":0,"dur":250000,"pid":1,"tid":1},` +
+		`{"name":"thread_name","ph":"M","ts":0,"pid":1,"tid":2,"args":{"name":"test:run"}},` +
+		`{"name":"test:run","cat":"task","ph":"X","ts":500000,"dur":1000000,"pid":1,"tid":2,` +
+		`"args":{"error":"exit 1"}}` +
+		`],"displayTimeUnit":"ms"}` + "\n"
+	assert.Check(t, is.Equal(expected, out.String()))
+}
+
+func TestTraceWriteEmpty(t *testing.T) {
+	out := new(bytes.Buffer)
+	assert.NilError(t, NewTrace().Write(out))
+	assert.Check(t, is.Equal(`{"traceEvents":[],"displayTimeUnit":"ms"}`+"\n", out.String()))
+}
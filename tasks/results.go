@@ -0,0 +1,189 @@
+package tasks
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	"github.com/dnephin/dobi/tasks/types"
+)
+
+type taskStatus string
+
+const (
+	statusSucceeded taskStatus = "succeeded"
+	statusFailed    taskStatus = "failed"
+	statusSkipped   taskStatus = "skipped"
+)
+
+// taskResult is the outcome of a task from a run
+type taskResult struct {
+	name   task.Name
+	status taskStatus
+	err    error
+	// reason a task was skipped when it was skipped by its when condition
+	reason string
+}
+
+// blocksDependents returns true if the tasks which depend on this task should
+// not run. A task which was skipped by its when condition does not block the
+// tasks which depend on it.
+func (r *taskResult) blocksDependents() bool {
+	switch r.status {
+	case statusSucceeded:
+		return false
+	case statusSkipped:
+		return r.err != nil
+	default:
+		return true
+	}
+}
+
+// runResults records the outcome of every task in a run. It is safe for
+// concurrent use.
+type runResults struct {
+	lock    sync.Mutex
+	results []*taskResult
+	// running holds a lock for each task, which is held while the task runs
+	running map[string]*sync.Mutex
+}
+
+func newRunResults() *runResults {
+	return &runResults{running: make(map[string]*sync.Mutex)}
+}
+
+// lockTask blocks until no other run of the task is in progress, and returns a
+// function which releases the task
+func (r *runResults) lockTask(name task.Name) func() {
+	r.lock.Lock()
+	taskLock, ok := r.running[name.Name()]
+	if !ok {
+		taskLock = &sync.Mutex{}
+		r.running[name.Name()] = taskLock
+	}
+	r.lock.Unlock()
+
+	taskLock.Lock()
+	return taskLock.Unlock
+}
+
+func (r *runResults) get(name task.Name) *taskResult {
+	for _, result := range r.results {
+		if result.name.Equal(name) {
+			return result
+		}
+	}
+	return nil
+}
+
+// succeeded returns true if the task has already run successfully in this run
+func (r *runResults) succeeded(name task.Name) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	result := r.get(name)
+	return result != nil && result.status == statusSucceeded
+}
+
+// add the outcome of a task. A task which ran more than once keeps the outcome
+// of the last run.
+func (r *runResults) add(name task.Name, err error) {
+	status := statusSucceeded
+	if err != nil {
+		status = statusFailed
+	}
+	r.set(&taskResult{name: name, status: status, err: err})
+}
+
+func (r *runResults) skip(name task.Name, err error) {
+	r.set(&taskResult{name: name, status: statusSkipped, err: err})
+}
+
+// skipCondition records a task which was not run because its when condition
+// was false
+func (r *runResults) skipCondition(name task.Name, reason string) {
+	r.set(&taskResult{name: name, status: statusSkipped, reason: reason})
+}
+
+func (r *runResults) set(result *taskResult) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if existing := r.get(result.name); existing != nil {
+		*existing = *result
+		return
+	}
+	r.results = append(r.results, result)
+}
+
+// failedDependency returns an error if any dependency of the task failed or was
+// skipped.
+func (r *runResults) failedDependency(taskConfig types.TaskConfig) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, dep := range taskConfig.Dependencies() {
+		result := r.get(task.ParseName(dep))
+		if result != nil && result.blocksDependents() {
+			return fmt.Errorf("dependency %s %s", result.name, result.status)
+		}
+	}
+	return nil
+}
+
+// firstError returns the error from the first task that failed
+func (r *runResults) firstError() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, result := range r.results {
+		if result.status == statusFailed {
+			return result.err
+		}
+	}
+	return nil
+}
+
+// summaryError returns an error with the number of failed tasks, if any tasks
+// failed.
+func (r *runResults) summaryError() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	failed := 0
+	for _, result := range r.results {
+		if result.status == statusFailed {
+			failed++
+		}
+	}
+	if failed == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d tasks failed", failed, len(r.results))
+}
+
+// finish returns the error for the run. With Settings.KeepGoing a summary of
+// every task is printed, and the error includes the number of failed tasks.
+// Otherwise the error is the error from the first failed task.
+func (r *runResults) finish(ctx *context.ExecuteContext) error {
+	if !ctx.Settings.KeepGoing {
+		return r.firstError()
+	}
+	r.printSummary(ctx.Stdout)
+	return r.summaryError()
+}
+
+func (r *runResults) printSummary(out io.Writer) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	fmt.Fprintln(out, "Summary:")
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "  TASK\tSTATUS\tERROR")
+	for _, result := range r.results {
+		errMsg := result.reason
+		if result.err != nil {
+			errMsg = result.err.Error()
+		}
+		fmt.Fprintf(writer, "  %s\t%s\t%s\n", result.name, result.status, errMsg)
+	}
+	writer.Flush() // nolint: errcheck
+}
@@ -0,0 +1,109 @@
+package tasks
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/events"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func newKeepGoingCollection(ran map[string]bool) *TaskCollection {
+	run := func(name string, err error) func(*context.ExecuteContext) error {
+		return func(*context.ExecuteContext) error {
+			ran[name] = true
+			return err
+		}
+	}
+	return newTestCollection(
+		newFakeTaskConfig("lint:run", nil, run("lint", fmt.Errorf("exit 1"))),
+		newFakeTaskConfig("unit:run", nil, run("unit", nil)),
+		newFakeTaskConfig("docs:run", []string{"lint"}, run("docs", nil)),
+		newFakeTaskConfig("all:run", []string{"unit", "docs"}, run("all", nil)),
+	)
+}
+
+func TestExecuteTasksKeepGoing(t *testing.T) {
+	ran := map[string]bool{}
+	ctx := newTestContext()
+	ctx.Settings.KeepGoing = true
+
+	err := executeTasks(ctx, newKeepGoingCollection(ran))
+	assert.Check(t, is.Error(err, "1 of 4 tasks failed"))
+	assert.Check(t, is.DeepEqual(map[string]bool{"lint": true, "unit": true}, ran))
+
+	expected := `Summary:
+  TASK      STATUS     ERROR
+  lint:run  failed     failed to execute task "lint:run": exit 1
+  unit:run  succeeded  
+  docs:run  skipped    dependency lint:run failed
+  all:run   skipped    dependency docs:run skipped
+`
+	assert.Check(t, is.Equal(expected, ctx.Stdout.(*bytes.Buffer).String()))
+}
+
+func TestExecuteTasksWithoutKeepGoing(t *testing.T) {
+	ran := map[string]bool{}
+	ctx := newTestContext()
+
+	err := executeTasks(ctx, newKeepGoingCollection(ran))
+	assert.Check(t, is.Error(err, `failed to execute task "lint:run": exit 1`))
+	assert.Check(t, is.DeepEqual(map[string]bool{"lint": true}, ran))
+	assert.Check(t, is.Equal("", ctx.Stdout.(*bytes.Buffer).String()))
+}
+
+func TestExecuteTasksParallelKeepGoing(t *testing.T) {
+	ran := map[string]bool{}
+	ctx := newTestContext()
+	ctx.Settings.KeepGoing = true
+
+	err := executeTasksParallel(ctx, newKeepGoingCollection(ran), 1)
+	assert.Check(t, is.Error(err, "1 of 4 tasks failed"))
+	assert.Check(t, is.DeepEqual(map[string]bool{"lint": true, "unit": true}, ran))
+	assert.Check(t, is.Contains(ctx.Stdout.(*bytes.Buffer).String(),
+		"all:run   skipped    dependency docs:run skipped"))
+}
+
+func TestExecuteTasksParallelSkipsDependentsAfterFailure(t *testing.T) {
+	ran := map[string]bool{}
+	ctx := newTestContext()
+
+	err := executeTasksParallel(ctx, newKeepGoingCollection(ran), 1)
+	assert.Check(t, is.Error(err, `failed to execute task "lint:run": exit 1`))
+	// unit:run does not depend on lint:run, so it may start before lint:run fails
+	assert.Check(t, ran["lint"])
+	assert.Check(t, !ran["docs"], "expected docs:run to be skipped")
+	assert.Check(t, !ran["all"], "expected all:run to be skipped")
+}
+
+type eventRecorder struct {
+	events []string
+}
+
+func (r *eventRecorder) Handle(event events.Event) {
+	r.events = append(r.events, fmt.Sprintf("%s %s", event.Type, event.Task))
+}
+
+func TestExecuteTasksKeepGoingEmitsEvents(t *testing.T) {
+	recorder := &eventRecorder{}
+	ctx := newTestContext()
+	ctx.Settings.KeepGoing = true
+	ctx.Events = events.NewEmitter(recorder)
+
+	err := executeTasks(ctx, newKeepGoingCollection(map[string]bool{}))
+	assert.Check(t, is.Error(err, "1 of 4 tasks failed"))
+	expected := []string{
+		"started lint:run",
+		"stale lint:run",
+		"finished lint:run",
+		"started unit:run",
+		"stale unit:run",
+		"finished unit:run",
+		"skipped docs:run",
+		"skipped all:run",
+	}
+	assert.Check(t, is.DeepEqual(expected, recorder.events))
+}
@@ -0,0 +1,50 @@
+// Package retry runs a task action again when it fails, following the retry
+// policy of a resource.
+package retry
+
+import (
+	gocontext "context"
+	"time"
+
+	"github.com/dnephin/dobi/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// Always is a retryable func which retries every error
+func Always(error) bool {
+	return true
+}
+
+// Do calls run until it succeeds, the policy is out of attempts, or run returns
+// an error which is not retryable. The wait before each retry starts at the
+// backoff of the policy and doubles after each retry. Retrying stops when ctx
+// is done.
+func Do(
+	ctx gocontext.Context,
+	logger log.FieldLogger,
+	policy config.RetryConfig,
+	retryable func(error) bool,
+	run func() error,
+) error {
+	attempts := policy.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	wait := policy.Backoff.Value()
+
+	for attempt := 1; ; attempt++ {
+		err := run()
+		if err == nil || attempt >= attempts || !retryable(err) {
+			return err
+		}
+		logger.Warnf("Attempt %d of %d failed: %s. Retrying in %s",
+			attempt, attempts, err, wait)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
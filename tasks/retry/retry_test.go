@@ -0,0 +1,63 @@
+package retry
+
+import (
+	gocontext "context"
+	"fmt"
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	log "github.com/sirupsen/logrus"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestDoRetriesUntilSuccess(t *testing.T) {
+	calls := 0
+	run := func() error {
+		calls++
+		if calls < 3 {
+			return fmt.Errorf("attempt %d", calls)
+		}
+		return nil
+	}
+	policy := config.RetryConfig{Attempts: 5}
+	err := Do(gocontext.Background(), log.NewEntry(log.New()), policy, Always, run)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(3, calls))
+}
+
+func TestDoStopsAfterAttempts(t *testing.T) {
+	calls := 0
+	run := func() error {
+		calls++
+		return fmt.Errorf("attempt %d", calls)
+	}
+	policy := config.RetryConfig{Attempts: 2}
+	err := Do(gocontext.Background(), log.NewEntry(log.New()), policy, Always, run)
+	assert.Check(t, is.Error(err, "attempt 2"))
+	assert.Check(t, is.Equal(2, calls))
+}
+
+func TestDoStopsOnErrorThatIsNotRetryable(t *testing.T) {
+	calls := 0
+	run := func() error {
+		calls++
+		return fmt.Errorf("attempt %d", calls)
+	}
+	never := func(error) bool { return false }
+	policy := config.RetryConfig{Attempts: 3}
+	err := Do(gocontext.Background(), log.NewEntry(log.New()), policy, never, run)
+	assert.Check(t, is.Error(err, "attempt 1"))
+	assert.Check(t, is.Equal(1, calls))
+}
+
+func TestDoWithoutAttempts(t *testing.T) {
+	calls := 0
+	run := func() error {
+		calls++
+		return fmt.Errorf("failed")
+	}
+	err := Do(gocontext.Background(), log.NewEntry(log.New()), config.RetryConfig{}, Always, run)
+	assert.Check(t, is.Error(err, "failed"))
+	assert.Check(t, is.Equal(1, calls))
+}
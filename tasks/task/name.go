@@ -84,3 +84,16 @@ func splitTaskActionName(name string) (string, string) {
 		return name, ""
 	}
 }
+
+// NamespaceSeparator separates the namespace of an included config file from
+// the name of a resource
+const NamespaceSeparator = "."
+
+// QualifyName returns the task name with the namespace added to the resource
+// name. An empty name is returned unchanged.
+func QualifyName(namespace, name string) string {
+	if namespace == "" || name == "" {
+		return name
+	}
+	return namespace + NamespaceSeparator + name
+}
@@ -1,7 +1,9 @@
 package tasks
 
 import (
+	gocontext "context"
 	"fmt"
+	"io"
 	"strings"
 	"time"
 
@@ -13,9 +15,11 @@ import (
 	"github.com/dnephin/dobi/tasks/compose"
 	"github.com/dnephin/dobi/tasks/context"
 	"github.com/dnephin/dobi/tasks/env"
+	"github.com/dnephin/dobi/tasks/events"
 	"github.com/dnephin/dobi/tasks/image"
 	"github.com/dnephin/dobi/tasks/job"
 	"github.com/dnephin/dobi/tasks/mount"
+	"github.com/dnephin/dobi/tasks/plugin"
 	"github.com/dnephin/dobi/tasks/task"
 	"github.com/dnephin/dobi/tasks/types"
 	log "github.com/sirupsen/logrus"
@@ -24,6 +28,17 @@ import (
 // TaskCollection is a collection of Task objects
 type TaskCollection struct {
 	tasks []types.TaskConfig
+	hooks map[string]*taskHooks
+	// named is the set of tasks named by a hook, for the collection of a hook.
+	// The other tasks in the collection are dependencies of the named tasks.
+	named map[string]bool
+}
+
+// taskHooks are the tasks collected for the hooks of a task
+type taskHooks struct {
+	before    *TaskCollection
+	after     *TaskCollection
+	onFailure *TaskCollection
 }
 
 func (c *TaskCollection) add(task types.TaskConfig) {
@@ -45,8 +60,13 @@ func (c *TaskCollection) Get(name task.Name) types.TaskConfig {
 	return nil
 }
 
+// hooksFor returns the hooks of the task, or nil if the task has no hooks
+func (c *TaskCollection) hooksFor(name task.Name) *taskHooks {
+	return c.hooks[name.MapKey()]
+}
+
 func newTaskCollection() *TaskCollection {
-	return &TaskCollection{}
+	return &TaskCollection{hooks: make(map[string]*taskHooks)}
 }
 
 func collectTasks(options RunOptions) (*TaskCollection, error) {
@@ -63,32 +83,91 @@ type collectionState struct {
 
 func collect(options RunOptions, state *collectionState) (*TaskCollection, error) {
 	for _, taskname := range options.Tasks {
-		taskname := task.ParseName(taskname)
-		resourceName := taskname.Resource()
-		resource, ok := options.Config.Resources[resourceName]
-		if !ok {
-			return nil, fmt.Errorf("resource %q does not exist", resourceName)
-		}
-
-		taskConfig, err := buildTaskConfig(resourceName, taskname.Action(), resource)
-		if err != nil {
+		if _, err := collectTask(options, state, taskname); err != nil {
 			return nil, err
 		}
+	}
+	return state.tasks, nil
+}
 
-		if state.taskStack.Contains(taskConfig.Name()) {
-			return nil, fmt.Errorf(
-				"Invalid dependency cycle: %s", strings.Join(state.taskStack.Names(), ", "))
-		}
-		state.taskStack.Push(taskConfig.Name())
+// collectTask adds the dependencies of the task, and then the task, to the
+// collection. The TaskConfig of the task is returned.
+func collectTask(
+	options RunOptions,
+	state *collectionState,
+	name string,
+) (types.TaskConfig, error) {
+	taskname := task.ParseName(name)
+	resourceName := taskname.Resource()
+	resource, ok := options.Config.Resources[resourceName]
+	if !ok {
+		return nil, fmt.Errorf("resource %q does not exist", resourceName)
+	}
 
-		options.Tasks = taskConfig.Dependencies()
-		if _, err := collect(options, state); err != nil {
-			return nil, err
+	taskConfig, err := buildTaskConfig(resourceName, taskname.Action(), resource)
+	if err != nil {
+		return nil, err
+	}
+
+	if state.taskStack.Contains(taskConfig.Name()) {
+		return nil, fmt.Errorf(
+			"Invalid dependency cycle: %s", strings.Join(state.taskStack.Names(), ", "))
+	}
+	state.taskStack.Push(taskConfig.Name())
+
+	options.Tasks = taskConfig.Dependencies()
+	if _, err := collect(options, state); err != nil {
+		return nil, err
+	}
+	if err := collectHooks(options, state, taskConfig); err != nil {
+		return nil, err
+	}
+	state.tasks.add(taskConfig)
+	state.taskStack.Pop() // nolint: errcheck
+	return taskConfig, nil
+}
+
+// collectHooks collects the tasks for each hook of the task. The hooks of
+// every task in a run are stored in the same map, so that hook tasks can have
+// hooks of their own. The task is still on the stack, so a hook which leads
+// back to the task is a dependency cycle.
+func collectHooks(
+	options RunOptions,
+	state *collectionState,
+	taskConfig types.TaskConfig,
+) error {
+	resourceHooks := config.ResourceHooks(taskConfig.Resource())
+	key := taskConfig.Name().MapKey()
+	if _, exists := state.tasks.hooks[key]; exists || len(resourceHooks.All()) == 0 {
+		return nil
+	}
+
+	collectHook := func(names []string) (*TaskCollection, error) {
+		hook := &TaskCollection{hooks: state.tasks.hooks, named: make(map[string]bool)}
+		hookState := &collectionState{tasks: hook, taskStack: state.taskStack}
+		for _, name := range names {
+			taskConfig, err := collectTask(options, hookState, name)
+			if err != nil {
+				return nil, err
+			}
+			hook.named[taskConfig.Name().MapKey()] = true
 		}
-		state.tasks.add(taskConfig)
-		state.taskStack.Pop() // nolint: errcheck
+		return hook, nil
 	}
-	return state.tasks, nil
+
+	hooks := &taskHooks{}
+	var err error
+	if hooks.before, err = collectHook(resourceHooks.Before); err != nil {
+		return err
+	}
+	if hooks.after, err = collectHook(resourceHooks.After); err != nil {
+		return err
+	}
+	if hooks.onFailure, err = collectHook(resourceHooks.OnFailure); err != nil {
+		return err
+	}
+	state.tasks.hooks[key] = hooks
+	return nil
 }
 
 // TODO: some way to make this a registry
@@ -106,8 +185,10 @@ func buildTaskConfig(name, action string, resource config.Resource) (types.TaskC
 		return env.GetTaskConfig(name, action, conf)
 	case *config.ComposeConfig:
 		return compose.GetTaskConfig(name, action, conf)
+	case *config.PluginConfig:
+		return plugin.GetTaskConfig(name, action, conf)
 	default:
-		panic(fmt.Sprintf("Unexpected config type %T", conf))
+		return nil, fmt.Errorf("unexpected config type %T", conf)
 	}
 }
 
@@ -121,53 +202,282 @@ func reversed(tasks []types.Task) []types.Task {
 
 func executeTasks(ctx *context.ExecuteContext, tasks *TaskCollection) error {
 	startedTasks := []types.Task{}
-
 	defer func() {
-		logging.Log.Debug("stopping tasks")
-		for _, startedTask := range reversed(startedTasks) {
-			if err := startedTask.Stop(ctx); err != nil {
-				logging.Log.Warnf("Failed to stop task %q: %s", startedTask.Name(), err)
-			}
-		}
+		stopTasks(ctx, startedTasks)
 	}()
 
-	logging.Log.Debug("executing tasks")
+	results := newRunResults()
+	ctx.Logger.Debug("executing tasks")
 	for _, taskConfig := range tasks.All() {
-		resource, err := taskConfig.Resource().Resolve(ctx.Env)
-		if err != nil {
+		if err := results.failedDependency(taskConfig); err != nil {
+			skipTask(ctx, results, taskConfig.Name(), err)
+			continue
+		}
+		if ctx.Context.Err() != nil {
+			skipTask(ctx, results, taskConfig.Name(), errInterrupted)
+			continue
+		}
+
+		started, err := executeEnabledTask(ctx, results, tasks, taskConfig)
+		startedTasks = append(startedTasks, started...)
+		if err != nil && !ctx.Settings.KeepGoing {
 			return err
 		}
-		ctx.Resources.Add(taskConfig.Name().Resource(), resource)
+	}
+	return results.finish(ctx)
+}
+
+// stopTasks runs the Stop of every started task. The tasks are stopped with a
+// new Context, so they are still stopped when the run was interrupted.
+func stopTasks(ctx *context.ExecuteContext, startedTasks []types.Task) {
+	ctx.Logger.Debug("stopping tasks")
+	ctx = ctx.WithContext(gocontext.Background())
+	for _, startedTask := range reversed(startedTasks) {
+		if err := startedTask.Stop(ctx); err != nil {
+			ctx.Logger.Warnf("Failed to stop task %q: %s", startedTask.Name(), err)
+		}
+	}
+}
+
+// startTask resolves the variables in the resource of the task, and returns
+// the Task for the resolved resource.
+func startTask(ctx *context.ExecuteContext, taskConfig types.TaskConfig) (types.Task, error) {
+	resource, err := taskConfig.Resource().Resolve(ctx.Env)
+	if err != nil {
+		return nil, err
+	}
+	ctx.Resources.Add(taskConfig.Name().Resource(), resource)
+	return taskConfig.Task(resource), nil
+}
+
+// executeEnabledTask runs the task, and its hooks, when its when condition is
+// true, and records the outcome in results. When the condition is false the
+// task is recorded as skipped, and the resource of the task is resolved so that
+// it can be used by the tasks which depend on it. Every task which was started
+// is returned, so that it can be stopped.
+func executeEnabledTask(
+	ctx *context.ExecuteContext,
+	results *runResults,
+	tasks *TaskCollection,
+	taskConfig types.TaskConfig,
+) ([]types.Task, error) {
+	name := taskConfig.Name()
+	enabled, reason, err := checkCondition(ctx, taskConfig)
+	switch {
+	case err != nil:
+		ctx.Emit(events.NewEvent(events.Started, name))
+		ctx.Emit(events.NewFinishedEvent(name, 0, false, err))
+		results.add(name, err)
+		return nil, err
+	case !enabled:
+		ctx.Logger.WithFields(log.Fields{"task": name}).Infof("Skipped, %s", reason)
+		if _, err := startTask(ctx, taskConfig); err != nil {
+			skipTask(ctx, results, name, err)
+			return nil, nil
+		}
+		event := events.NewEvent(events.Skipped, name)
+		event.Reason = reason
+		ctx.Emit(event)
+		results.skipCondition(name, reason)
+		return nil, nil
+	}
 
-		currentTask := taskConfig.Task(resource)
-		startedTasks = append(startedTasks, currentTask)
-		start := time.Now()
-		logging.Log.WithFields(log.Fields{"time": start, "task": currentTask}).Debug("Start")
+	started, err := executeWithHooks(ctx, results, tasks, taskConfig)
+	results.add(name, err)
+	return started, err
+}
 
-		depsModified := hasModifiedDeps(ctx, taskConfig.Dependencies())
-		modified, err := currentTask.Run(ctx, depsModified)
-		if err != nil {
-			return fmt.Errorf("failed to execute task %q: %s", currentTask.Name(), err)
+// executeWithHooks runs the task along with its before and after hooks. The
+// on-failure hooks are run when the task, or one of its hooks, fails. They are
+// run with a new Context, so that they still run when the run was interrupted.
+// The outcome of each hook task is recorded in results. Every task which was
+// started is returned, so that it can be stopped.
+func executeWithHooks(
+	ctx *context.ExecuteContext,
+	results *runResults,
+	tasks *TaskCollection,
+	taskConfig types.TaskConfig,
+) ([]types.Task, error) {
+	hooks := tasks.hooksFor(taskConfig.Name())
+	if hooks == nil {
+		currentTask, err := executeExclusive(ctx, results, taskConfig)
+		return appendStarted(nil, currentTask), err
+	}
+
+	started, err := runHooks(ctx, results, tasks, hooks.before)
+	if err == nil {
+		var currentTask types.Task
+		currentTask, err = executeExclusive(ctx, results, taskConfig)
+		started = appendStarted(started, currentTask)
+	}
+	if err == nil {
+		var afterStarted []types.Task
+		afterStarted, err = runHooks(ctx, results, tasks, hooks.after)
+		started = append(started, afterStarted...)
+	}
+	if err != nil {
+		failureCtx := ctx.WithContext(gocontext.Background())
+		failureStarted, hookErr := runHooks(failureCtx, results, tasks, hooks.onFailure)
+		started = append(started, failureStarted...)
+		if hookErr != nil {
+			ctx.Logger.Warnf("Failed to run on-failure hooks of %s: %s",
+				taskConfig.Name(), hookErr)
+		}
+	}
+	return started, err
+}
+
+// runHooks runs each task of a hook in order, and stops at the first failure.
+// The outcome of each task is recorded in results. The tasks named by the hook
+// run every time the hook runs, but their dependencies are not run again when
+// they already succeeded in this run.
+func runHooks(
+	ctx *context.ExecuteContext,
+	results *runResults,
+	tasks *TaskCollection,
+	hook *TaskCollection,
+) ([]types.Task, error) {
+	started := []types.Task{}
+	for _, taskConfig := range hook.All() {
+		name := taskConfig.Name()
+		if !hook.named[name.MapKey()] && results.succeeded(name) {
+			ctx.Logger.Debugf("Not running %s again for a hook", name)
+			continue
 		}
-		if modified {
-			ctx.SetModified(currentTask.Name())
+		enabled, reason, err := checkCondition(ctx, taskConfig)
+		switch {
+		case err != nil:
+			results.add(name, err)
+			return started, err
+		case !enabled:
+			ctx.Logger.Debugf("Skipped hook %s, %s", name, reason)
+			results.skipCondition(name, reason)
+			continue
+		}
+
+		hookStarted, err := executeWithHooks(ctx, results, tasks, taskConfig)
+		started = append(started, hookStarted...)
+		results.add(name, err)
+		if err != nil {
+			return started, err
 		}
-		logging.Log.WithFields(log.Fields{
-			"elapsed": time.Since(start),
-			"task":    currentTask,
-		}).Debug("Complete")
 	}
-	return nil
+	return started, nil
+}
+
+// executeExclusive runs the task once no other run of the same task is in
+// progress. With Settings.Parallel a task can be both a task of the run and a
+// hook of another task, or a hook of more than one task, and the runs of the
+// task must not overlap.
+func executeExclusive(
+	ctx *context.ExecuteContext,
+	results *runResults,
+	taskConfig types.TaskConfig,
+) (types.Task, error) {
+	unlock := results.lockTask(taskConfig.Name())
+	defer unlock()
+	return executeTask(ctx, taskConfig)
+}
+
+func appendStarted(started []types.Task, currentTask types.Task) []types.Task {
+	if currentTask == nil {
+		return started
+	}
+	return append(started, currentTask)
+}
+
+// checkCondition evaluates the when condition of the task. The reason is
+// returned when the condition is false. The condition is checked immediately
+// before the task runs, so that it can use variables set by earlier tasks, and
+// the dependencies of the task are run even when the condition is false.
+func checkCondition(
+	ctx *context.ExecuteContext,
+	taskConfig types.TaskConfig,
+) (bool, string, error) {
+	resource, ok := taskConfig.Resource().(config.ConditionalResource)
+	if !ok {
+		return true, "", nil
+	}
+	when := resource.Condition()
+	enabled, err := config.EvaluateCondition(ctx.Env, when)
+	switch {
+	case err != nil:
+		return false, "", fmt.Errorf("failed to execute task %q: %s", taskConfig.Name(), err)
+	case !enabled:
+		return false, fmt.Sprintf("when %q is false", when), nil
+	default:
+		return true, "", nil
+	}
+}
+
+// executeTask resolves the resource of the task and runs the task. The Task is
+// returned whenever the resource was resolved, so that it can be stopped even
+// if it failed to run.
+func executeTask(
+	ctx *context.ExecuteContext,
+	taskConfig types.TaskConfig,
+) (types.Task, error) {
+	name := taskConfig.Name()
+	start := time.Now()
+	ctx.Emit(events.NewEvent(events.Started, name))
+
+	var modified bool
+	currentTask, err := startTask(ctx, taskConfig)
+	if err == nil {
+		modified, err = runTask(ctx, taskConfig, currentTask)
+	}
+	ctx.Emit(events.NewFinishedEvent(name, time.Since(start), modified, err))
+	return currentTask, err
+}
+
+func runTask(
+	ctx *context.ExecuteContext,
+	taskConfig types.TaskConfig,
+	currentTask types.Task,
+) (bool, error) {
+	start := time.Now()
+	ctx.Logger.WithFields(log.Fields{"time": start, "task": currentTask}).Debug("Start")
+
+	if _, ok := currentTask.(types.StaleChecker); !ok {
+		ctx.Emit(events.NewStalenessEvent(currentTask.Name(), true, "always runs"))
+	}
+	depsModified := hasModifiedDeps(ctx, taskConfig.Dependencies())
+	modified, err := currentTask.Run(ctx, depsModified)
+	if err != nil {
+		return false, fmt.Errorf("failed to execute task %q: %s", currentTask.Name(), err)
+	}
+	if modified {
+		ctx.SetModified(currentTask.Name())
+	}
+	ctx.Logger.WithFields(log.Fields{
+		"elapsed": time.Since(start),
+		"task":    currentTask,
+	}).Debug("Complete")
+	return modified, nil
+}
+
+// skipTask records a task which was not run because of an earlier failure
+func skipTask(ctx *context.ExecuteContext, results *runResults, name task.Name, err error) {
+	event := events.NewEvent(events.Skipped, name)
+	event.Reason = err.Error()
+	ctx.Emit(event)
+	results.skip(name, err)
 }
 
 func hasModifiedDeps(ctx *context.ExecuteContext, deps []string) bool {
+	return len(modifiedDependencies(ctx, deps)) > 0
+}
+
+// modifiedDependencies returns the names of the dependencies which were
+// modified
+func modifiedDependencies(ctx *context.ExecuteContext, deps []string) []string {
+	modified := []string{}
 	for _, dep := range deps {
-		taskName := task.ParseName(dep)
-		if ctx.IsModified(taskName) {
-			return true
+		if ctx.IsModified(task.ParseName(dep)) {
+			modified = append(modified, dep)
 		}
 	}
-	return false
+	return modified
 }
 
 // RunOptions are the options supported by Run
@@ -177,6 +487,35 @@ type RunOptions struct {
 	Tasks     []string
 	Quiet     bool
 	BindMount bool
+	// Parallel is the maximum number of tasks to run at the same time. Tasks
+	// are run one at a time in dependency order when it is less than 2.
+	Parallel int
+	// DryRun prints the tasks which would run, and why, without running them
+	DryRun bool
+	// KeepGoing continues to run every task which does not depend on a failed
+	// task, and prints a summary of all tasks at the end of the run
+	KeepGoing bool
+	// Timeout is the maximum time a job, image, or compose task may run when the
+	// resource does not set a timeout. 0 means no timeout.
+	Timeout time.Duration
+	// Listeners receive an event at each point in the lifecycle of every task
+	Listeners []events.Listener
+	// Args are passed to the command of each job in Tasks, or in an alias in
+	// Tasks
+	Args []string
+	// Stdout and Stderr receive the output of every task. They default to
+	// os.Stdout and os.Stderr.
+	Stdout io.Writer
+	Stderr io.Writer
+	// Context cancels the run when it is done, in the same way as an
+	// interrupt. Defaults to context.Background().
+	Context gocontext.Context
+	// HandleSignals stops the run when the process receives SIGINT or SIGTERM,
+	// and kills the running tasks when it receives the signal again
+	HandleSignals bool
+	// Logger receives the log messages from the run. Defaults to the logger in
+	// the logging package.
+	Logger *log.Logger
 }
 
 func getNames(options RunOptions) []string {
@@ -191,6 +530,28 @@ func getNames(options RunOptions) []string {
 	return options.Tasks
 }
 
+func getLogger(options RunOptions) *log.Logger {
+	if options.Logger != nil {
+		return options.Logger
+	}
+	return logging.Log
+}
+
+// setOutput sets the output writers, logger, and parent Context from the
+// options
+func setOutput(ctx *context.ExecuteContext, options RunOptions) {
+	if options.Stdout != nil {
+		ctx.Stdout = options.Stdout
+	}
+	if options.Stderr != nil {
+		ctx.Stderr = options.Stderr
+	}
+	if options.Context != nil {
+		ctx.Context = options.Context
+	}
+	ctx.Logger = getLogger(options)
+}
+
 // Run one or more tasks
 func Run(options RunOptions) error {
 	options.Tasks = getNames(options)
@@ -202,6 +563,7 @@ func Run(options RunOptions) error {
 		options.Config.Meta.ExecID,
 		options.Config.Meta.Project,
 		options.Config.WorkingDir,
+		getLogger(options),
 	)
 	if err != nil {
 		return err
@@ -212,10 +574,36 @@ func Run(options RunOptions) error {
 		return err
 	}
 
-	ctx := context.NewExecuteContext(
-		options.Config,
-		options.Client,
-		execEnv,
-		context.NewSettings(options.Quiet, options.BindMount))
-	return executeTasks(ctx, tasks)
+	settings := context.NewSettings(options.Quiet, options.BindMount)
+	settings.Parallel = options.Parallel
+	settings.KeepGoing = options.KeepGoing
+	settings.Timeout = options.Timeout
+	settings.Args, err = argsForJobs(options)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.NewExecuteContext(options.Config, options.Client, execEnv, settings)
+	ctx.Events = events.NewEmitter(options.Listeners...)
+	setOutput(ctx, options)
+	for _, node := range newTaskGraph(tasks) {
+		ctx.Emit(events.NewEvent(events.Collected, node.config.Name()))
+	}
+
+	if options.DryRun {
+		printPlan(ctx.Stdout, planTasks(ctx, tasks))
+		return nil
+	}
+
+	if options.HandleSignals {
+		var stop func()
+		ctx.Context, ctx.Kill, stop = cancelOnInterrupt(ctx.Context, ctx.Logger)
+		defer stop()
+	}
+	if options.Parallel > 1 {
+		err = executeTasksParallel(ctx, tasks, options.Parallel)
+	} else {
+		err = executeTasks(ctx, tasks)
+	}
+	return interruptedError(ctx.Context, err)
 }
@@ -1,9 +1,16 @@
 package tasks
 
 import (
+	"bytes"
+	"fmt"
 	"testing"
 
 	"github.com/dnephin/dobi/config"
+	testconfig "github.com/dnephin/dobi/internal/test/config"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/events"
+	"github.com/dnephin/dobi/tasks/task"
+	"github.com/dnephin/dobi/tasks/types"
 	"gotest.tools/v3/assert"
 	is "gotest.tools/v3/assert/cmp"
 )
@@ -45,3 +52,220 @@ func TestCollectTasksDoesNotErrorOnDuplicateTask(t *testing.T) {
 	assert.Check(t, is.Nil(err))
 	assert.Check(t, is.Len(tasks.All(), 3))
 }
+
+func withCondition(taskConfig types.TaskConfig, when string) types.TaskConfig {
+	resource := taskConfig.Resource().(*testconfig.FakeResource)
+	resource.When = when
+	return taskConfig
+}
+
+func TestExecuteTasksSkipsTaskWhenConditionIsFalse(t *testing.T) {
+	ran := map[string]bool{}
+	run := func(name string) func(*context.ExecuteContext) error {
+		return func(*context.ExecuteContext) error {
+			ran[name] = true
+			return nil
+		}
+	}
+	tasks := newTestCollection(
+		withCondition(newFakeTaskConfig("push:run", nil, run("push")), "branch == main"),
+		withCondition(newFakeTaskConfig("lint:run", nil, run("lint")), "main == main"),
+		newFakeTaskConfig("release:run", []string{"push", "lint"}, run("release")),
+	)
+	ctx := newTestContext()
+	ctx.Settings.KeepGoing = true
+
+	assert.NilError(t, executeTasks(ctx, tasks))
+	assert.Check(t, is.DeepEqual(map[string]bool{"lint": true, "release": true}, ran))
+
+	expected := `Summary:
+  TASK         STATUS     ERROR
+  push:run     skipped    when "branch == main" is false
+  lint:run     succeeded  
+  release:run  succeeded  
+`
+	assert.Check(t, is.Equal(expected, ctx.Stdout.(*bytes.Buffer).String()))
+}
+
+func TestExecuteTasksRunsDependenciesOfSkippedTask(t *testing.T) {
+	ran := map[string]bool{}
+	run := func(name string) func(*context.ExecuteContext) error {
+		return func(*context.ExecuteContext) error {
+			ran[name] = true
+			return nil
+		}
+	}
+	tasks := newTestCollection(
+		newFakeTaskConfig("docs:run", nil, run("docs")),
+		withCondition(
+			newFakeTaskConfig("push:run", []string{"docs"}, run("push")), "branch == main"),
+	)
+	ctx := newTestContext()
+	ctx.Settings.KeepGoing = true
+
+	assert.NilError(t, executeTasks(ctx, tasks))
+	assert.Check(t, is.DeepEqual(map[string]bool{"docs": true}, ran))
+
+	expected := `Summary:
+  TASK      STATUS     ERROR
+  docs:run  succeeded  
+  push:run  skipped    when "branch == main" is false
+`
+	assert.Check(t, is.Equal(expected, ctx.Stdout.(*bytes.Buffer).String()))
+}
+
+func TestCollectTasksWithHooks(t *testing.T) {
+	test := &config.AliasConfig{Tasks: []string{}}
+	test.Hooks = config.HooksConfig{
+		Before:    []string{"db"},
+		OnFailure: []string{"logs"},
+	}
+	runOptions := RunOptions{
+		Config: &config.Config{
+			Resources: map[string]config.Resource{
+				"test": test,
+				"db":   aliasWithDeps([]string{}),
+				"logs": aliasWithDeps([]string{"db"}),
+			},
+		},
+		Tasks: []string{"test"},
+	}
+	tasks, err := collectTasks(runOptions)
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(tasks.All(), 1))
+
+	hooks := tasks.hooksFor(tasks.All()[0].Name())
+	assert.Assert(t, hooks != nil)
+	assert.Check(t, is.Len(hooks.before.All(), 1))
+	assert.Check(t, is.Len(hooks.after.All(), 0))
+	assert.Check(t, is.Len(hooks.onFailure.All(), 2))
+	named := map[string]bool{task.NewDefaultName("logs", "run").MapKey(): true}
+	assert.Check(t, is.DeepEqual(named, hooks.onFailure.named))
+}
+
+func TestCollectTasksErrorsOnCyclicHooks(t *testing.T) {
+	test := &config.AliasConfig{Tasks: []string{}}
+	test.Hooks = config.HooksConfig{After: []string{"cleanup"}}
+	cleanup := &config.AliasConfig{Tasks: []string{"test"}}
+	runOptions := RunOptions{
+		Config: &config.Config{
+			Resources: map[string]config.Resource{
+				"test":    test,
+				"cleanup": cleanup,
+			},
+		},
+		Tasks: []string{"test"},
+	}
+	_, err := collectTasks(runOptions)
+	assert.Check(t, is.ErrorContains(err,
+		"Invalid dependency cycle: test:run, cleanup:run"))
+}
+
+func TestExecuteTasksWithHooks(t *testing.T) {
+	ran := []string{}
+	run := func(name string, err error) func(*context.ExecuteContext) error {
+		return func(*context.ExecuteContext) error {
+			ran = append(ran, name)
+			return err
+		}
+	}
+	build := newFakeTaskConfig("build:run", nil, run("build", nil))
+	test := newFakeTaskConfig("test:run", []string{"build"}, run("test", fmt.Errorf("exit 1")))
+	tasks := newTestCollection(build, test)
+	tasks.hooks[build.Name().MapKey()] = &taskHooks{
+		before:    newTestCollection(newFakeTaskConfig("setup:run", nil, run("setup", nil))),
+		after:     newTestCollection(newFakeTaskConfig("publish:run", nil, run("publish", nil))),
+		onFailure: newTestCollection(),
+	}
+	tasks.hooks[test.Name().MapKey()] = &taskHooks{
+		before:    newTestCollection(newFakeTaskConfig("db:run", nil, run("db up", nil))),
+		after:     newTestCollection(newFakeTaskConfig("down:run", nil, run("db down", nil))),
+		onFailure: newTestCollection(newFakeTaskConfig("logs:run", nil, run("db logs", nil))),
+	}
+
+	err := executeTasks(newTestContext(), tasks)
+	assert.Check(t, is.Error(err, `failed to execute task "test:run": exit 1`))
+	expected := []string{"setup", "build", "publish", "db up", "test", "db logs"}
+	assert.Check(t, is.DeepEqual(expected, ran))
+}
+
+func TestExecuteTasksRecordsHookResults(t *testing.T) {
+	run := func(err error) func(*context.ExecuteContext) error {
+		return func(*context.ExecuteContext) error { return err }
+	}
+	test := newFakeTaskConfig("test:run", nil, run(nil))
+	tasks := newTestCollection(test)
+	tasks.hooks[test.Name().MapKey()] = &taskHooks{
+		before:    newTestCollection(newFakeTaskConfig("db:run", nil, run(nil))),
+		after:     newTestCollection(newFakeTaskConfig("down:run", nil, run(fmt.Errorf("gone")))),
+		onFailure: newTestCollection(newFakeTaskConfig("logs:run", nil, run(nil))),
+	}
+	ctx := newTestContext()
+	ctx.Settings.KeepGoing = true
+
+	err := executeTasks(ctx, tasks)
+	assert.Check(t, is.Error(err, "2 of 4 tasks failed"))
+	expected := `Summary:
+  TASK      STATUS     ERROR
+  db:run    succeeded  
+  down:run  failed     failed to execute task "down:run": gone
+  logs:run  succeeded  
+  test:run  failed     failed to execute task "down:run": gone
+`
+	assert.Check(t, is.Equal(expected, ctx.Stdout.(*bytes.Buffer).String()))
+}
+
+func TestExecuteTasksEmitsStalenessOfHookTasks(t *testing.T) {
+	noop := func(*context.ExecuteContext) error { return nil }
+	test := newFakeTaskConfig("test:run", nil, noop)
+	tasks := newTestCollection(test)
+	tasks.hooks[test.Name().MapKey()] = &taskHooks{
+		before:    newTestCollection(newFakeTaskConfig("db:run", nil, noop)),
+		after:     newTestCollection(),
+		onFailure: newTestCollection(),
+	}
+	recorder := &eventRecorder{}
+	ctx := newTestContext()
+	ctx.Events = events.NewEmitter(recorder)
+
+	assert.NilError(t, executeTasks(ctx, tasks))
+	expected := []string{
+		"started db:run",
+		"stale db:run",
+		"finished db:run",
+		"started test:run",
+		"stale test:run",
+		"finished test:run",
+	}
+	assert.Check(t, is.DeepEqual(expected, recorder.events))
+}
+
+func TestExecuteTasksDoesNotRunDependenciesOfHooksAgain(t *testing.T) {
+	ran := []string{}
+	run := func(name string) func(*context.ExecuteContext) error {
+		return func(*context.ExecuteContext) error {
+			ran = append(ran, name)
+			return nil
+		}
+	}
+	build := newFakeTaskConfig("build:run", nil, run("build"))
+	test := newFakeTaskConfig("test:run", []string{"build"}, run("test"))
+	lint := newFakeTaskConfig("lint:run", []string{"build"}, run("lint"))
+	tasks := newTestCollection(build, test, lint)
+
+	// db:run is the task named by the hook, and build:run is its dependency
+	db := newFakeTaskConfig("db:run", []string{"build"}, run("db"))
+	hook := newTestCollection(build, db)
+	hook.named = map[string]bool{db.Name().MapKey(): true}
+	for _, taskConfig := range []types.TaskConfig{test, lint} {
+		tasks.hooks[taskConfig.Name().MapKey()] = &taskHooks{
+			before:    hook,
+			after:     newTestCollection(),
+			onFailure: newTestCollection(),
+		}
+	}
+
+	assert.NilError(t, executeTasks(newTestContext(), tasks))
+	expected := []string{"build", "db", "test", "db", "lint"}
+	assert.Check(t, is.DeepEqual(expected, ran))
+}
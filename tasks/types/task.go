@@ -1,9 +1,13 @@
 package types
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/dnephin/dobi/config"
 	"github.com/dnephin/dobi/logging"
 	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/events"
 	"github.com/dnephin/dobi/tasks/task"
 )
 
@@ -15,6 +19,86 @@ type Task interface {
 	Stop(*context.ExecuteContext) error
 }
 
+// Staleness is the result of checking if a task needs to run
+type Staleness struct {
+	Stale  bool
+	Reason string
+	// Details are the values which were compared to decide if the task is
+	// stale, like the newest source file and the modified time of the artifact
+	Details []string
+}
+
+// Explain returns a copy of the Staleness with the details added
+func (s Staleness) Explain(details ...string) Staleness {
+	s.Details = append(append([]string{}, s.Details...), details...)
+	return s
+}
+
+// FormatTime formats a time for the details of a Staleness
+func FormatTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Format("2006-01-02 15:04:05.000 MST")
+}
+
+// FileDetail describes the newest file found by a search, for the details of
+// a Staleness
+func FileDetail(kind string, path string, modified time.Time) string {
+	if path == "" {
+		return fmt.Sprintf("%s: no files found", kind)
+	}
+	return fmt.Sprintf("%s: %s modified %s", kind, path, FormatTime(modified))
+}
+
+// Stale returns a Staleness for a task that needs to run
+func Stale(reason string) Staleness {
+	return Staleness{Stale: true, Reason: reason}
+}
+
+// Fresh returns a Staleness for a task that is up to date
+func Fresh(reason string) Staleness {
+	return Staleness{Reason: reason}
+}
+
+// CheckStaleness returns the Staleness from isStale, and sends a Stale or Fresh
+// event for the task. A task with modified dependencies is always stale, so
+// isStale is not called.
+func CheckStaleness(
+	ctx *context.ExecuteContext,
+	name task.Name,
+	depsModified bool,
+	isStale func() (Staleness, error),
+) (Staleness, error) {
+	staleness := Stale("dependencies were modified")
+	if !depsModified {
+		var err error
+		endSpan := ctx.Span(name, "check staleness")
+		staleness, err = isStale()
+		endSpan()
+		if err != nil {
+			return staleness, err
+		}
+	}
+	ctx.Emit(events.NewStalenessEvent(name, staleness.Stale, staleness.Reason))
+	return staleness, nil
+}
+
+// StaleChecker is implemented by tasks which can check if they need to run
+// without making any changes. The Run of a StaleChecker checks if the task is
+// stale with CheckStaleness, so that the Stale or Fresh event is sent. The
+// event for a task which is not a StaleChecker is sent before it runs.
+type StaleChecker interface {
+	IsStale(*context.ExecuteContext) (Staleness, error)
+}
+
+// Planner is implemented by tasks which set variables used by the tasks which
+// follow them. Plan records the variables in the ExecEnv of the context without
+// running the task or changing the environment of the process.
+type Planner interface {
+	Plan(*context.ExecuteContext) error
+}
+
 // RunFunc is a function which performs the task. It received a context and a
 // bool indicating if any dependencies were modified. It should return true if
 // the resource was modified, otherwise false.
@@ -0,0 +1,330 @@
+package tasks
+
+import (
+	gocontext "context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/execenv"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/types"
+	"github.com/dnephin/dobi/utils/fs"
+	log "github.com/sirupsen/logrus"
+)
+
+// Watch runs the tasks, and then watches the files used by each task. When the
+// files change the tasks which use them, and every task which depends on those
+// tasks, are run again. The files are polled once every interval, and changes
+// are collected until the files stop changing for one interval. A run which is
+// in progress when a file changes is cancelled. Watch returns when dobi
+// receives SIGINT or SIGTERM.
+func Watch(options RunOptions, interval time.Duration) error {
+	options.Tasks = getNames(options)
+	if len(options.Tasks) == 0 {
+		return fmt.Errorf("no task to watch, and no default task defined")
+	}
+
+	execEnv, err := execenv.NewExecEnvFromConfig(
+		options.Config.Meta.ExecID,
+		options.Config.Meta.Project,
+		options.Config.WorkingDir,
+		getLogger(options),
+	)
+	if err != nil {
+		return err
+	}
+
+	tasks, err := collectTasks(options)
+	if err != nil {
+		return err
+	}
+
+	w := &watcher{
+		logger:  getLogger(options),
+		options: options,
+		execEnv: execEnv,
+		tasks:   tasks,
+		nodes:   newTaskGraph(tasks),
+	}
+	return w.watch(interval)
+}
+
+type watcher struct {
+	logger  *log.Logger
+	options RunOptions
+	execEnv *execenv.ExecEnv
+	tasks   *TaskCollection
+	nodes   []*taskNode
+}
+
+type watchRun struct {
+	logger *log.Logger
+	cancel gocontext.CancelFunc
+	kill   gocontext.CancelFunc
+	done   chan error
+}
+
+// stop cancels the run, and waits for it to finish. A signal received while
+// waiting kills the running tasks.
+func (r *watchRun) stop(signals <-chan os.Signal) {
+	r.cancel()
+	select {
+	case sig := <-signals:
+		r.logger.Warnf("Received %s, killing tasks", sig)
+		r.kill()
+		<-r.done
+	case <-r.done:
+	}
+}
+
+// nolint: gocyclo
+func (w *watcher) watch(interval time.Duration) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var running *watchRun
+	pending := make(map[string]bool)
+	for _, node := range w.nodes {
+		pending[node.config.Name().Name()] = true
+	}
+	snapshot := w.snapshot()
+
+	for {
+		var runDone chan error
+		if running != nil {
+			runDone = running.done
+		}
+
+		select {
+		case sig := <-signals:
+			w.logger.Debugf("Received %s, stopping watch", sig)
+			if running != nil {
+				running.stop(signals)
+			}
+			return nil
+		case err := <-runDone:
+			running = nil
+			if err != nil {
+				w.logger.Error(err)
+			}
+			// files changed after the last tick, while the run was finishing,
+			// are pending for the next run
+			snapshot, _ = w.collectChanges(snapshot, pending)
+			w.logger.Info("Watching for changes")
+		case <-ticker.C:
+			var changed bool
+			snapshot, changed = w.collectChanges(snapshot, pending)
+			if changed {
+				if running != nil {
+					running.cancel()
+				}
+				continue
+			}
+
+			if running == nil && len(pending) > 0 {
+				running = w.start(affectedTasks(w.nodes, pending))
+				pending = make(map[string]bool)
+			}
+		}
+	}
+}
+
+func (w *watcher) start(affected map[string]bool) *watchRun {
+	gctx, cancel := gocontext.WithCancel(gocontext.Background())
+	kill, cancelKill := gocontext.WithCancel(gocontext.Background())
+	run := &watchRun{
+		logger: w.logger,
+		cancel: cancel,
+		kill:   cancelKill,
+		done:   make(chan error, 1),
+	}
+	go func() {
+		defer cancel()
+		defer cancelKill()
+		run.done <- w.run(gctx, kill, affected)
+	}()
+	return run
+}
+
+// run every affected task. Tasks which are not affected are only resolved,
+// so their resources are available to the affected tasks.
+func (w *watcher) run(
+	gctx gocontext.Context,
+	kill gocontext.Context,
+	affected map[string]bool,
+) error {
+	settings := context.NewSettings(w.options.Quiet, w.options.BindMount)
+	settings.Timeout = w.options.Timeout
+	ctx := context.NewExecuteContext(w.options.Config, w.options.Client, w.execEnv, settings)
+	ctx.Context = gctx
+	ctx.Kill = kill
+	ctx.Logger = w.logger
+
+	startedTasks := []types.Task{}
+	defer func() {
+		stopTasks(ctx, startedTasks)
+	}()
+
+	results := newRunResults()
+
+	for _, node := range w.nodes {
+		enabled, reason, err := checkCondition(ctx, node.config)
+		if err != nil {
+			return err
+		}
+		if !enabled {
+			ctx.Logger.Debugf("Skipped %s, %s", node.config.Name(), reason)
+		}
+		if !enabled || !affected[node.config.Name().Name()] {
+			if _, err := startTask(ctx, node.config); err != nil {
+				return err
+			}
+			continue
+		}
+
+		started, err := executeWithHooks(ctx, results, w.tasks, node.config)
+		startedTasks = append(startedTasks, started...)
+		if err != nil {
+			if gctx.Err() != nil {
+				return fmt.Errorf("interrupted task %q", node.config.Name())
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// collectChanges takes a new snapshot, and adds every task with files which
+// changed since the previous snapshot to pending. Files created by tasks are
+// excluded from the snapshot, so a run does not cause changes for itself.
+// collectChanges returns the new snapshot, and true if any files changed.
+func (w *watcher) collectChanges(
+	previous map[string]string,
+	pending map[string]bool,
+) (map[string]string, bool) {
+	current := w.snapshot()
+	changed := changedTasks(previous, current)
+	if len(changed) == 0 {
+		return current, false
+	}
+	w.logger.Infof("Files changed for %v", changed)
+	for _, name := range changed {
+		pending[name] = true
+	}
+	return current, true
+}
+
+// snapshot returns a fingerprint of the files watched by each task. Watching
+// polls the files, so every snapshot walks the watched paths and reads the
+// name, size, and modified time of each file. Tasks which watch the same paths
+// share a fingerprint, so the paths are only walked once.
+func (w *watcher) snapshot() map[string]string {
+	conf := w.options.Config
+	excludes := watchExcludes(conf)
+	snapshot := make(map[string]string)
+	fingerprints := make(map[string]string)
+	for _, node := range w.nodes {
+		paths := watchedPaths(conf, node.config.Resource())
+		if len(paths) == 0 {
+			continue
+		}
+		key := strings.Join(paths, "\x00")
+		fingerprint, ok := fingerprints[key]
+		if !ok {
+			var err error
+			fingerprint, err = fs.Fingerprint(&fs.LastModifiedSearch{
+				Root:     conf.WorkingDir,
+				Paths:    paths,
+				Excludes: excludes,
+			})
+			if err != nil {
+				w.logger.Warnf("Failed to watch files for %s: %s", node.config.Name(), err)
+			}
+			fingerprints[key] = fingerprint
+		}
+		snapshot[node.config.Name().Name()] = fingerprint
+	}
+	return snapshot
+}
+
+// watchedPaths returns the files used by the resource. For jobs these are the
+// sources and bind mounts, and for images the build context and Dockerfile.
+func watchedPaths(conf *config.Config, resource config.Resource) []string {
+	switch res := resource.(type) {
+	case *config.JobConfig:
+		paths := append([]string{}, res.Sources.Paths()...)
+		for _, name := range res.Mounts {
+			mount, ok := conf.Resources[name].(*config.MountConfig)
+			if ok && mount.IsBind() {
+				paths = append(paths, mount.Bind)
+			}
+		}
+		return paths
+	case *config.ImageConfig:
+		if !res.IsBuildable() {
+			return nil
+		}
+		paths := []string{res.Context}
+		if res.Dockerfile != "" {
+			paths = append(paths, filepath.Join(res.Context, res.Dockerfile))
+		}
+		return paths
+	default:
+		return nil
+	}
+}
+
+// watchExcludes returns the patterns for files which are created by tasks, so
+// a task does not trigger another run by creating its own artifacts
+func watchExcludes(conf *config.Config) []string {
+	excludes := []string{".dobi"}
+	for _, resource := range conf.Resources {
+		if job, ok := resource.(*config.JobConfig); ok {
+			excludes = append(excludes, job.Artifact.Globs()...)
+		}
+	}
+	return excludes
+}
+
+// changedTasks returns the names of the tasks with a different fingerprint
+func changedTasks(previous, current map[string]string) []string {
+	changed := []string{}
+	for name, fingerprint := range current {
+		if previous[name] != fingerprint {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// affectedTasks returns the set of tasks that were changed, and every task
+// which depends on a changed task
+func affectedTasks(nodes []*taskNode, changed map[string]bool) map[string]bool {
+	affected := make(map[string]bool)
+	for _, node := range nodes {
+		name := node.config.Name().Name()
+		if changed[name] {
+			affected[name] = true
+			continue
+		}
+		for _, dep := range node.deps {
+			if affected[dep.config.Name().Name()] {
+				affected[name] = true
+				break
+			}
+		}
+	}
+	return affected
+}
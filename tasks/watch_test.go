@@ -0,0 +1,90 @@
+package tasks
+
+import (
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	"github.com/dnephin/dobi/tasks/types"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+func TestChangedTasks(t *testing.T) {
+	previous := map[string]string{"one:run": "a", "two:run": "b"}
+	current := map[string]string{"one:run": "a", "two:run": "c", "three:run": "d"}
+	assert.Check(t, is.DeepEqual(
+		[]string{"three:run", "two:run"}, changedTasks(previous, current)))
+}
+
+func TestAffectedTasks(t *testing.T) {
+	noop := func(*context.ExecuteContext) error { return nil }
+	nodes := newTaskGraph(newTestCollection(
+		newFakeTaskConfig("builder:build", nil, noop),
+		newFakeTaskConfig("source:create", nil, noop),
+		newFakeTaskConfig("test:run", []string{"builder:build", "source:create"}, noop),
+		newFakeTaskConfig("docs:run", []string{"source:create"}, noop),
+	))
+
+	affected := affectedTasks(nodes, map[string]bool{"builder:build": true})
+	expected := map[string]bool{"builder:build": true, "test:run": true}
+	assert.Check(t, is.DeepEqual(expected, affected))
+}
+
+func TestWatchedPaths(t *testing.T) {
+	conf := &config.Config{
+		Resources: map[string]config.Resource{
+			"source": &config.MountConfig{Bind: "./src", Path: "/code"},
+			"cache":  &config.MountConfig{Name: "cache", Path: "/cache"},
+		},
+	}
+	job := &config.JobConfig{Mounts: []string{"source", "cache"}}
+	assert.Check(t, is.DeepEqual([]string{"./src"}, watchedPaths(conf, job)))
+
+	image := &config.ImageConfig{Context: "docker", Dockerfile: "Dockerfile.build"}
+	assert.Check(t, is.DeepEqual(
+		[]string{"docker", "docker/Dockerfile.build"}, watchedPaths(conf, image)))
+
+	assert.Check(t, is.Len(watchedPaths(conf, &config.AliasConfig{}), 0))
+}
+
+func TestCollectChangesAddsChangedTasksToPending(t *testing.T) {
+	dir := fs.NewDir(t, "watch", fs.WithDir("src", fs.WithFile("main.go", "package main")))
+	defer dir.Remove()
+
+	job := &config.JobConfig{Mounts: []string{"source"}}
+	conf := &config.Config{
+		WorkingDir: dir.Path(),
+		Resources: map[string]config.Resource{
+			"source": &config.MountConfig{Bind: "src", Path: "/code"},
+			"test":   job,
+		},
+	}
+	taskConfig := types.NewTaskConfig(
+		task.NewDefaultName("test", "run"), job, func() []string { return nil },
+		func(name task.Name, _ config.Resource) types.Task {
+			return &fakeTask{name: name}
+		})
+	w := &watcher{
+		logger:  logging.Log,
+		options: RunOptions{Config: conf},
+		nodes:   newTaskGraph(newTestCollection(taskConfig)),
+	}
+
+	previous := w.snapshot()
+	pending := make(map[string]bool)
+	current, changed := w.collectChanges(previous, pending)
+	assert.Check(t, !changed)
+	assert.Check(t, is.Len(pending, 0))
+
+	// a file changed while a run was finishing is compared to the snapshot
+	// from before the run
+	fs.Apply(t, dir, fs.WithFile("src/main.go", "package main // changed"))
+	current, changed = w.collectChanges(current, pending)
+	assert.Check(t, changed)
+	assert.Check(t, is.DeepEqual(map[string]bool{"test:run": true}, pending))
+	assert.Check(t, current["test:run"] != previous["test:run"])
+}
@@ -0,0 +1,124 @@
+package fs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Digest returns a sha256 digest of the content of all the files found by the
+// search. The digest includes the path of each file relative to the Root, so it
+// changes when a file is added, removed, renamed, or modified, but not when
+// only the modified time of a file changes.
+func Digest(search *LastModifiedSearch) (string, error) {
+	digest := sha256.New()
+	err := walkFiles(search, func(relPath string, path string, info os.FileInfo) error {
+		return hashFile(digest, relPath, path, info)
+	})
+	if err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(digest.Sum(nil)), nil
+}
+
+// Fingerprint returns a sha256 digest of the path, size, and modified time of
+// all the files found by the search. It is much faster than Digest, and
+// changes when any file is added, removed, or modified. Paths which do not
+// exist are ignored.
+func Fingerprint(search *LastModifiedSearch) (string, error) {
+	digest := sha256.New()
+	existing := &LastModifiedSearch{Root: search.Root, Excludes: search.Excludes}
+	for _, path := range search.Paths {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(search.Root, path)
+		}
+		if _, err := os.Lstat(path); err == nil {
+			existing.Paths = append(existing.Paths, path)
+		}
+	}
+
+	err := walkFiles(existing, func(relPath string, _ string, info os.FileInfo) error {
+		fmt.Fprintf(digest, "%s\x00%d\x00%d\x00",
+			filepath.ToSlash(relPath), info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(digest.Sum(nil)), nil
+}
+
+// walkFiles calls each for every file found by the search, in lexical order.
+// Directories are not passed to each.
+func walkFiles(
+	search *LastModifiedSearch,
+	each func(relPath string, path string, info os.FileInfo) error,
+) error {
+	isExcluded, err := newExcludeMatcher(search)
+	if err != nil {
+		return err
+	}
+
+	walker := func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsPermission(err) {
+				return fmt.Errorf("can't stat '%s'", filePath)
+			}
+			return err
+		}
+
+		skip, err := isExcluded(filePath)
+		switch {
+		case err != nil:
+			return err
+		case skip && info.IsDir():
+			return filepath.SkipDir
+		case skip, info.IsDir():
+			return nil
+		}
+
+		relPath, err := filepath.Rel(search.Root, filePath)
+		if err != nil {
+			return err
+		}
+		return each(relPath, filePath, info)
+	}
+
+	for _, path := range search.Paths {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(search.Root, path)
+		}
+		if err := filepath.Walk(path, walker); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func hashFile(digest hash.Hash, relPath string, path string, info os.FileInfo) error {
+	fmt.Fprintf(digest, "%s\x00%d\x00", filepath.ToSlash(relPath), info.Size())
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(digest, target)
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close() // nolint: errcheck
+	_, err = io.Copy(digest, file)
+	return err
+}
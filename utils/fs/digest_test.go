@@ -0,0 +1,74 @@
+package fs
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+func TestDigestIgnoresModifiedTime(t *testing.T) {
+	tmpdir := fs.NewDir(t, "test-digest-ignores-modified-time",
+		fs.WithFile("Dockerfile", "FROM alpine"),
+		fs.WithDir("src", fs.WithFile("main.go", "package main")))
+	defer tmpdir.Remove()
+
+	search := &LastModifiedSearch{Root: tmpdir.Path(), Paths: []string{"."}}
+	before, err := Digest(search)
+	assert.NilError(t, err)
+
+	mtime := time.Now().AddDate(0, 0, 10)
+	assert.NilError(t, touch(tmpdir.Join("src", "main.go"), mtime))
+	content := []byte("package main")
+	assert.NilError(t, ioutil.WriteFile(tmpdir.Join("src", "main.go"), content, 0644))
+	after, err := Digest(search)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(before, after))
+}
+
+func TestDigestChangesWithContent(t *testing.T) {
+	tmpdir := fs.NewDir(t, "test-digest-changes-with-content",
+		fs.WithFile("Dockerfile", "FROM alpine"),
+		fs.WithFile("excluded", "one"))
+	defer tmpdir.Remove()
+
+	search := &LastModifiedSearch{
+		Root:     tmpdir.Path(),
+		Paths:    []string{"."},
+		Excludes: []string{"excluded"},
+	}
+	before, err := Digest(search)
+	assert.NilError(t, err)
+
+	assert.NilError(t, ioutil.WriteFile(tmpdir.Join("excluded"), []byte("two"), 0644))
+	after, err := Digest(search)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(before, after))
+
+	content := []byte("FROM busybox")
+	assert.NilError(t, ioutil.WriteFile(tmpdir.Join("Dockerfile"), content, 0644))
+	after, err = Digest(search)
+	assert.NilError(t, err)
+	assert.Check(t, before != after)
+}
+
+func TestFingerprintChangesWithModifiedTime(t *testing.T) {
+	tmpdir := fs.NewDir(t, "test-fingerprint-changes-with-modified-time",
+		fs.WithFile("main.go", "package main"))
+	defer tmpdir.Remove()
+
+	search := &LastModifiedSearch{
+		Root:  tmpdir.Path(),
+		Paths: []string{"main.go", "missing"},
+	}
+	before, err := Fingerprint(search)
+	assert.NilError(t, err)
+
+	assert.NilError(t, touch(tmpdir.Join("main.go"), time.Now().AddDate(0, 0, 10)))
+	after, err := Fingerprint(search)
+	assert.NilError(t, err)
+	assert.Check(t, before != after)
+}
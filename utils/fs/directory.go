@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/docker/docker/pkg/fileutils"
@@ -23,27 +24,22 @@ type LastModifiedSearch struct {
 // LastModified returns the latest modified time for all the files and
 // directories. The files in each directory are checked for their last modified
 // time.
+func LastModified(search *LastModifiedSearch) (time.Time, error) {
+	_, latest, err := NewestFile(search)
+	return latest, err
+}
+
+// NewestFile returns the path and modified time of the file or directory with
+// the latest modified time. The path is empty if there are no files.
 // TODO: use go routines to speed this up
 // nolint: gocyclo
-func LastModified(search *LastModifiedSearch) (time.Time, error) {
+func NewestFile(search *LastModifiedSearch) (string, time.Time, error) {
 	var latest time.Time
-	var err error
+	var newest string
 
-	pm, err := fileutils.NewPatternMatcher(search.Excludes)
+	isExcluded, err := newExcludeMatcher(search)
 	if err != nil {
-		return time.Time{}, err
-	}
-
-	isExcluded := func(path string) (bool, error) {
-		relPath, err := filepath.Rel(search.Root, path)
-		if err != nil {
-			return false, err
-		}
-		if relPath == "." {
-			// Don't let them exclude everything, kind of silly.
-			return false, nil
-		}
-		return pm.Matches(relPath)
+		return "", time.Time{}, err
 	}
 
 	walker := func(filePath string, info os.FileInfo, err error) error {
@@ -66,7 +62,7 @@ func LastModified(search *LastModifiedSearch) (time.Time, error) {
 		}
 
 		if info.ModTime().After(latest) {
-			latest = info.ModTime()
+			latest, newest = info.ModTime(), filePath
 		}
 		return nil
 	}
@@ -78,27 +74,60 @@ func LastModified(search *LastModifiedSearch) (time.Time, error) {
 
 		info, err := os.Stat(path)
 		if err != nil {
-			return latest, fmt.Errorf("internal error: %w", err)
+			return newest, latest, fmt.Errorf("internal error: %w", err)
 		}
 		switch info.IsDir() {
 		case false:
 			skip, err := isExcluded(path)
 			switch {
 			case err != nil:
-				return time.Time{}, err
+				return "", time.Time{}, err
 			case skip:
 				continue
 			}
 
 			if info.ModTime().After(latest) {
-				latest = info.ModTime()
+				latest, newest = info.ModTime(), path
 				continue
 			}
 		default:
 			if err := filepath.Walk(path, walker); err != nil {
-				return latest, err
+				return newest, latest, err
 			}
 		}
 	}
-	return latest, nil
+	return relativeTo(search.Root, newest), latest, nil
+}
+
+// relativeTo returns path relative to root when path is inside root
+func relativeTo(root, path string) string {
+	if path == "" {
+		return path
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return rel
+}
+
+// newExcludeMatcher returns a function which returns true if a path matches
+// one of the Excludes of the search
+func newExcludeMatcher(search *LastModifiedSearch) (func(string) (bool, error), error) {
+	pm, err := fileutils.NewPatternMatcher(search.Excludes)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(path string) (bool, error) {
+		relPath, err := filepath.Rel(search.Root, path)
+		if err != nil {
+			return false, err
+		}
+		if relPath == "." {
+			// Don't let them exclude everything, kind of silly.
+			return false, nil
+		}
+		return pm.Matches(relPath)
+	}, nil
 }
@@ -128,3 +128,24 @@ func touch(name string, mtime time.Time) error {
 
 	return os.Chtimes(name, mtime, mtime)
 }
+
+func TestNewestFile(t *testing.T) {
+	tmpdir := fs.NewDir(t, "test-directory-newest-file",
+		fs.WithDir("a", fs.WithFile("old", ""), fs.WithFile("new", "")),
+		fs.WithFile("other", ""))
+	defer tmpdir.Remove()
+
+	past := time.Now().AddDate(0, 0, -10)
+	assert.NilError(t, touch(tmpdir.Join("a", "old"), past))
+	assert.NilError(t, touch(tmpdir.Join("other"), past))
+	mtime := time.Now().AddDate(0, 0, 10)
+	assert.NilError(t, touch(tmpdir.Join("a", "new"), mtime))
+
+	path, actual, err := NewestFile(&LastModifiedSearch{
+		Root:  tmpdir.Path(),
+		Paths: []string{"a", "other"},
+	})
+	assert.NilError(t, err)
+	assert.Check(t, cmp.Equal(path, "a/new"))
+	assert.Check(t, cmp.Equal(actual, mtime))
+}